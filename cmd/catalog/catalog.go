@@ -0,0 +1,59 @@
+package catalog
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/konveyor/kaffeine/kaffeine"
+
+	"github.com/spf13/cobra"
+)
+
+func NewCatalogCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "catalog",
+		Short: "Tools for authors of KRM function catalogs.",
+	}
+
+	validate := &cobra.Command{
+		Use:   "validate [file]",
+		Short: "Checks a catalog file for problems",
+		Long: `Checks a catalog file for a known apiVersion and kind, missing required
+fields, unknown fields and duplicate functions, versions and platforms.
+Every problem is reported with its line number. Exits with a non-zero exit
+code if any problem is found.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file := args[0]
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			problems := kaffeine.ValidateCatalog(data)
+			if len(problems) == 0 {
+				fmt.Printf("'%s' is a valid catalog\n", file)
+				return nil
+			}
+
+			for _, p := range problems {
+				if p.Line > 0 {
+					fmt.Printf("%s:%d:%d: ", file, p.Line, p.Column)
+				} else {
+					fmt.Printf("%s: ", file)
+				}
+				if p.Path != "" {
+					fmt.Printf("%s: ", p.Path)
+				}
+				fmt.Println(p.Message)
+			}
+
+			cmd.SilenceUsage = true
+			return fmt.Errorf("found %d problems in '%s'", len(problems), file)
+		},
+	}
+
+	cmd.AddCommand(validate)
+
+	return cmd
+}
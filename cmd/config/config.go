@@ -2,17 +2,28 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 
 	"github.com/konveyor/kaffeine/kaffeine"
 
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v2"
 )
 
-// TODO: support both local and global config
-// global config determined by KAFFEINE_GLOBAL_CONFIG env variable.
-// If unset, defaults to ~/.kaffeine/config
+// The config is the project-local ".kaffeine/config.yaml" merged with the
+// global config, determined by the KAFFEINE_GLOBAL_CONFIG env variable.
+// If unset, defaults to ~/.kaffeine/config.yaml
 func NewConfigCommand() *cobra.Command {
+	var global bool
+	var showOrigin bool
+	var dryRun bool
+	var strict bool
+	var name string
+	var priority int
+	var disabled bool
+	var auth string
+	var refresh string
+
 	cmd := &cobra.Command{
 		Use:   "config",
 		Short: "Edit the configuration of kaffeine.",
@@ -21,34 +32,145 @@ func NewConfigCommand() *cobra.Command {
 	addCatalog := &cobra.Command{
 		Use:   "add-catalog [catalog uri]",
 		Short: "Adds catalog to list of managed catalogs in kaffeine",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Adds catalog to list of managed catalogs in kaffeine.
+
+The uri is a "file://" or HTTP(S) url, an OCI artifact such as
+"oci://ghcr.io/org/catalog:v1" (add "#<title>" to select one of its layers), or
+a file in a git repository such as
+"git+https://github.com/org/repo.git//catalog.yaml?ref=v1.2". Git catalogs are
+pinned to the commit they were fetched at until the next update.
+
+Catalogs are referred to by their name, derived from the uri unless given with
+--name. When several catalogs define the same function, its description comes
+from the catalog with the lowest priority value and their versions are
+combined, preferring that catalog for versions they share. Catalogs with the
+same priority keep the order they were added in. Use "name::group/name" to only
+use a single catalog.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			functionManager := kaffeine.NewFunctionManager("")
+			cc := kaffeine.CatalogConfig{
+				Name:     name,
+				Uri:      args[0],
+				Priority: priority,
+				Auth:     auth,
+				Refresh:  refresh,
+			}
+			if disabled {
+				enabled := false
+				cc.Enabled = &enabled
+			}
+
+			if global {
+				cfg := kaffeine.MakeGlobalConfig()
+				err := cfg.AddCatalog(cc)
+				if err != nil {
+					return err
+				}
+				cc = cfg.Catalogs[len(cfg.Catalogs)-1]
+
+				// Make sure the catalog can be used before adding it everywhere.
+				// Git catalogs are cloned for that, into a directory that is
+				// removed afterwards.
+				if cc.IsEnabled() {
+					dir, err := os.MkdirTemp("", "kaffeine-")
+					if err != nil {
+						return err
+					}
+					defer os.RemoveAll(dir)
+					catman := kaffeine.MakeCatalogManager(dir)
+					catman.Strict = strict
+					catman.Settings[cc.Uri] = cc
+					catman.Client, err = kaffeine.MakeHTTPClient(&cfg)
+					if err != nil {
+						return err
+					}
+					_, err = catman.GetExternalCatalog(cc.Uri)
+					if err != nil {
+						return err
+					}
+				}
+
+				if dryRun {
+					fmt.Printf("+ catalog %s (in '%s')\n", cc, cfg.FilePath)
+					return nil
+				}
+
+				err = cfg.Save()
+				if err != nil {
+					return err
+				}
 
-			uri := args[len(args)-1]
-			err := functionManager.CatMan.AddCatalogFromUri(uri)
+				fmt.Printf("Successfully added catalog '%s' to '%s'\n", cc.Name, cfg.FilePath)
+				return nil
+			}
+
+			newFunctionManager := kaffeine.NewFunctionManager
+			if dryRun {
+				newFunctionManager = kaffeine.NewReadOnlyFunctionManager
+			}
+			functionManager, err := newFunctionManager("")
 			if err != nil {
 				return err
 			}
 
+			functionManager.CatMan.Strict = strict
+			err = functionManager.AddCatalog(cc)
+			if err != nil {
+				return err
+			}
+			cc = functionManager.Cfg.Catalogs[len(functionManager.Cfg.Catalogs)-1]
+
+			if dryRun {
+				plan, err := functionManager.Plan()
+				if err != nil {
+					return err
+				}
+
+				fmt.Print(plan)
+				return nil
+			}
+
 			err = functionManager.Save()
 			if err != nil {
 				return err
 			}
 
-			fmt.Printf("Successfully added catalog '%s'\n", uri)
+			fmt.Printf("Successfully added catalog '%s'\n", cc.Name)
 			return nil
 		},
 	}
 
 	remCatalog := &cobra.Command{
-		Use:   "remove-catalog [catalog uri]",
+		Use:   "remove-catalog [catalog name or uri]",
 		Short: "Removes catalog to list of managed catalogs in kaffeine",
+		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			functionManager := kaffeine.NewFunctionManager("")
+			if global {
+				cfg := kaffeine.MakeGlobalConfig()
+				cc, err := cfg.RemoveCatalog(args[0])
+				if err != nil {
+					return err
+				}
+
+				err = cfg.Save()
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("Successfully removed catalog '%s' from '%s'\n", cc.Name, cfg.FilePath)
+				return nil
+			}
+
+			functionManager, err := kaffeine.NewFunctionManager("")
+			if err != nil {
+				return err
+			}
+
+			if err := checkLocalCatalog(functionManager.Cfg, args[0]); err != nil {
+				return err
+			}
 
-			uri := args[len(args)-1]
-			_, err := functionManager.CatMan.RemoveCatalog(uri)
+			cc, err := functionManager.RemoveCatalog(args[0])
 			if err != nil {
 				return err
 			}
@@ -58,36 +180,154 @@ func NewConfigCommand() *cobra.Command {
 				return err
 			}
 
-			fmt.Printf("Successfully removed catalog '%s'", uri)
+			fmt.Printf("Successfully removed catalog '%s'\n", cc.Name)
 			return nil
 		},
 	}
 
+	setPriority := &cobra.Command{
+		Use:   "set-priority [catalog name or uri] [priority]",
+		Short: "Sets the priority of a catalog, lower values taking precedence",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid priority '%s': %w", args[1], err)
+			}
+
+			return editCatalog(args[0], global, func(cc *kaffeine.CatalogConfig) {
+				cc.Priority = value
+			})
+		},
+	}
+
+	enableCatalog := &cobra.Command{
+		Use:   "enable [catalog name or uri]",
+		Short: "Enables a disabled catalog",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return editCatalog(args[0], global, func(cc *kaffeine.CatalogConfig) {
+				cc.Enabled = nil
+			})
+		},
+	}
+
+	disableCatalog := &cobra.Command{
+		Use:   "disable [catalog name or uri]",
+		Short: "Disables a catalog without removing it from the config",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return editCatalog(args[0], global, func(cc *kaffeine.CatalogConfig) {
+				disabled := false
+				cc.Enabled = &disabled
+			})
+		},
+	}
+
 	listConfig := &cobra.Command{
 		Use:   "list",
 		Short: "Lists current configuration",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			functionManager := kaffeine.NewFunctionManager("")
-
-			functionManager.UpdateConfig()
-			data, err := yaml.Marshal(functionManager.Cfg)
+			functionManager, err := kaffeine.NewReadOnlyFunctionManager("")
 			if err != nil {
 				return err
 			}
 
-			err = functionManager.Save()
+			functionManager.UpdateConfig()
+
+			output, _ := cmd.Flags().GetString("output")
+			if showOrigin {
+				output = kaffeine.OutputTable
+			}
+			if output == "" {
+				output = kaffeine.OutputYaml
+			}
+
+			data, err := kaffeine.Render(output, functionManager.Cfg.Redacted())
 			if err != nil {
 				return err
 			}
 
-			fmt.Println(string(data))
+			fmt.Print(string(data))
 			return nil
 		},
 	}
 
+	addCatalog.Flags().BoolVar(&global, "global", false, "add the catalog to the global config instead of the project's")
+	addCatalog.Flags().BoolVar(&strict, "strict", false, "refuse the catalog if it has problems (see 'kaffeine catalog validate')")
+	addCatalog.Flags().StringVar(&name, "name", "", "name to refer to the catalog by (default derived from the uri)")
+	addCatalog.Flags().IntVar(&priority, "priority", 0, "priority of the catalog, lower values taking precedence")
+	addCatalog.Flags().BoolVar(&disabled, "disabled", false, "add the catalog without loading it until it is enabled")
+	addCatalog.Flags().StringVar(&auth, "auth", "", "key of the credentials to fetch the catalog with")
+	addCatalog.Flags().StringVar(&refresh, "refresh", "", "minimum time between two refreshes of the catalog by update, such as '24h'")
+	addCatalog.Flags().BoolVar(&dryRun, "dry-run", false, "print what would change without changing anything")
+	for _, c := range []*cobra.Command{remCatalog, setPriority, enableCatalog, disableCatalog} {
+		c.Flags().BoolVar(&global, "global", false, "change the global config instead of the project's")
+	}
+	listConfig.Flags().BoolVar(&showOrigin, "show-origin", false, "show the file each value comes from")
+
 	cmd.AddCommand(addCatalog)
 	cmd.AddCommand(remCatalog)
+	cmd.AddCommand(setPriority)
+	cmd.AddCommand(enableCatalog)
+	cmd.AddCommand(disableCatalog)
 	cmd.AddCommand(listConfig)
 
 	return cmd
 }
+
+// Returns an error if the catalog comes from the global config rather than
+// the project's
+func checkLocalCatalog(cfg *kaffeine.Config, nameOrUri string) error {
+	cc, err := cfg.GetCatalog(nameOrUri)
+	if err != nil {
+		return err
+	}
+	if origin := cfg.Origin("catalogs/" + cc.Uri); origin != "" && origin != cfg.FilePath {
+		return fmt.Errorf("catalog '%s' comes from '%s' (use --global to change it)", cc.Name, origin)
+	}
+	return nil
+}
+
+// Changes the settings of a catalog in the project's config or the global one
+func editCatalog(nameOrUri string, global bool, edit func(cc *kaffeine.CatalogConfig)) error {
+	if global {
+		cfg := kaffeine.MakeGlobalConfig()
+		cc, err := cfg.GetCatalog(nameOrUri)
+		if err != nil {
+			return err
+		}
+		edit(cc)
+
+		err = cfg.Save()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Successfully changed catalog %s in '%s'\n", cc, cfg.FilePath)
+		return nil
+	}
+
+	functionManager, err := kaffeine.NewFunctionManager("")
+	if err != nil {
+		return err
+	}
+
+	if err := checkLocalCatalog(functionManager.Cfg, nameOrUri); err != nil {
+		return err
+	}
+
+	cc, _ := functionManager.Cfg.GetCatalog(nameOrUri)
+	edit(cc)
+	for _, err := range functionManager.LoadCatalogs() {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+
+	err = functionManager.Save()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully changed catalog %s\n", cc)
+	return nil
+}
@@ -0,0 +1,43 @@
+package info
+
+import (
+	"fmt"
+
+	"github.com/konveyor/kaffeine/kaffeine"
+
+	"github.com/spf13/cobra"
+)
+
+func NewInfoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "info [name]",
+		Short: "Shows the details of a function from the managed catalogs",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			functionManager, err := kaffeine.NewReadOnlyFunctionManager("")
+			if err != nil {
+				return err
+			}
+
+			info, err := functionManager.GetFunctionInfo(args[0])
+			if err != nil {
+				return err
+			}
+
+			output, _ := cmd.Flags().GetString("output")
+			if output == "" {
+				output = kaffeine.OutputTable
+			}
+
+			res, err := kaffeine.Render(output, info)
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(string(res))
+			return nil
+		},
+	}
+
+	return cmd
+}
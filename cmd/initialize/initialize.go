@@ -0,0 +1,43 @@
+package initialize
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/konveyor/kaffeine/kaffeine"
+
+	"github.com/spf13/cobra"
+)
+
+func NewInitCommand() *cobra.Command {
+	var catalogs []string
+
+	cmd := &cobra.Command{
+		Use:   "init [directory]",
+		Short: "Creates a kaffeine project in the current or given directory",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project := "."
+			if len(args) > 0 {
+				project = args[0]
+			}
+
+			dir, err := filepath.Abs(filepath.Join(project, ".kaffeine"))
+			if err != nil {
+				return err
+			}
+
+			err = kaffeine.InitDirectory(dir, catalogs)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Initialized kaffeine project in '%s'\n", dir)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&catalogs, "catalog", "c", nil, "uri of a catalog to start with (can be repeated)")
+
+	return cmd
+}
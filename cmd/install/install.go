@@ -1,6 +1,7 @@
 package install
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/konveyor/kaffeine/kaffeine"
@@ -9,11 +10,76 @@ import (
 )
 
 func NewInstallCommand() *cobra.Command {
+	var prerelease bool
+	var skipDigest bool
+	var platform string
+	var frozen bool
+	var dryRun bool
+
 	cmd := &cobra.Command{
 		Use:   "install [name]",
 		Short: "Searches the managed catalogs for a function with the specified name, and installs it",
+		Long: `Searches the managed catalogs for a function with the specified name, and installs it.
+
+A name like "catalog::group/name" only uses the catalog with the given uri or
+metadata name, also on later updates.
+
+With --frozen, the functions recorded in the lockfile are installed exactly as
+recorded, and the command fails if anything deviates from the lockfile.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			functionManager := kaffeine.NewFunctionManager("")
+			newFunctionManager := kaffeine.NewFunctionManager
+			if dryRun {
+				newFunctionManager = kaffeine.NewReadOnlyFunctionManager
+			}
+			functionManager, err := newFunctionManager("")
+			if err != nil {
+				return err
+			}
+			functionManager.AllowPrerelease = prerelease
+			functionManager.SkipDigestVerification = skipDigest
+			if platform != "" {
+				goos, goarch, err := kaffeine.ParsePlatform(platform)
+				if err != nil {
+					return err
+				}
+				functionManager.Platform = goos + "/" + goarch
+			}
+
+			if frozen {
+				err := functionManager.InstallFrozen()
+				if err != nil {
+					return err
+				}
+
+				if len(args) > 0 {
+					if _, err := functionManager.GetInstalledFunctionDefinition(args[0]); err != nil {
+						return fmt.Errorf("cannot install '%s' in frozen mode: %v", args[0], err)
+					}
+				}
+
+				if dryRun {
+					plan, err := functionManager.Plan()
+					if err != nil {
+						return err
+					}
+
+					fmt.Print(plan)
+					return nil
+				}
+
+				err = functionManager.Save()
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("Successfully installed %d KRM Functions from the lockfile\n", len(functionManager.Installed))
+				return nil
+			}
+
+			if len(args) == 0 {
+				return errors.New("requires the name of the function to install")
+			}
 
 			fname := args[len(args)-1]
 			fn, err := functionManager.AddFunctionDefinition(fname)
@@ -21,6 +87,16 @@ func NewInstallCommand() *cobra.Command {
 				return err
 			}
 
+			if dryRun {
+				plan, err := functionManager.Plan()
+				if err != nil {
+					return err
+				}
+
+				fmt.Print(plan)
+				return nil
+			}
+
 			err = functionManager.Save()
 			if err != nil {
 				return err
@@ -31,5 +107,11 @@ func NewInstallCommand() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().BoolVar(&prerelease, "pre", false, "allow pre-release versions when resolving the latest version")
+	cmd.Flags().BoolVar(&skipDigest, "insecure-skip-digest", false, "do not verify downloaded binaries against the sha256 declared in the catalog")
+	cmd.Flags().StringVar(&platform, "platform", "", "download binaries for the given 'os/arch' instead of the host platform")
+	cmd.Flags().BoolVar(&frozen, "frozen", false, "install exactly the functions recorded in the lockfile, failing on any deviation")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print what would change without changing anything")
+
 	return cmd
 }
@@ -13,19 +13,22 @@ func NewListCommand() *cobra.Command {
 		Use:   "list",
 		Short: "Lists the current installed catalog of functions",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			functionManager := kaffeine.NewFunctionManager("")
-
-			b, err := functionManager.GenerateInstalledCatalog()
+			functionManager, err := kaffeine.NewReadOnlyFunctionManager("")
 			if err != nil {
 				return err
 			}
 
-			err = functionManager.Save()
+			output, _ := cmd.Flags().GetString("output")
+			if output == "" {
+				output = kaffeine.OutputYaml
+			}
+
+			b, err := kaffeine.Render(output, functionManager.InstalledCatalog())
 			if err != nil {
 				return err
 			}
 
-			fmt.Println(string(b))
+			fmt.Print(string(b))
 			return nil
 		},
 	}
@@ -0,0 +1,84 @@
+package outdated
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/konveyor/kaffeine/kaffeine"
+
+	"github.com/spf13/cobra"
+)
+
+func NewOutdatedCommand() *cobra.Command {
+	var pre bool
+	var refresh bool
+
+	cmd := &cobra.Command{
+		Use:   "outdated",
+		Short: "Lists installed functions with newer versions available",
+		Long: `Lists installed functions with newer versions available. "Wanted" is the
+version update would install, which respects pegged versions and ranges.
+The catalogs are refreshed first like in update, but nothing is saved.
+Exits with a non-zero exit code if update would change any function.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			functionManager, err := kaffeine.NewReadOnlyFunctionManager("")
+			if err != nil {
+				return err
+			}
+			functionManager.AllowPrerelease = pre
+
+			functionManager.CatMan.ForceRefresh = refresh
+			_, errs := functionManager.CatMan.UpdateAllCatalogs()
+			failed := 0
+			for _, err := range errs {
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					failed++
+				}
+			}
+
+			outdated, errs := functionManager.Outdated()
+			for _, err := range errs {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+			}
+
+			output, _ := cmd.Flags().GetString("output")
+			if output == "" {
+				output = kaffeine.OutputTable
+			}
+
+			if len(outdated) == 0 && output == kaffeine.OutputTable {
+				fmt.Println("All functions are up to date")
+			} else {
+				res, err := kaffeine.Render(output, kaffeine.OutdatedFunctions(outdated))
+				if err != nil {
+					return err
+				}
+				fmt.Print(string(res))
+			}
+
+			// The versions compared against may be stale
+			if failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d catalogs could not be refreshed", failed)
+			}
+
+			updates := 0
+			for _, o := range outdated {
+				if o.UpdateAvailable() {
+					updates++
+				}
+			}
+			if updates > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d KRM Functions can be updated (run 'kaffeine update')", updates)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&pre, "pre", false, "compare against pre-release versions as well")
+	cmd.Flags().BoolVar(&refresh, "refresh", false, "refresh every catalog, even those whose refresh interval did not pass")
+
+	return cmd
+}
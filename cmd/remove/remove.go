@@ -2,6 +2,7 @@ package remove
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/konveyor/kaffeine/kaffeine"
 
@@ -9,11 +10,20 @@ import (
 )
 
 func NewRemoveCommand() *cobra.Command {
+	var dryRun bool
+
 	cmd := &cobra.Command{
 		Use:   "remove [name]",
 		Short: "Searches the managed catalogs for a function with the specified name, and installs it",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			functionManager := kaffeine.NewFunctionManager("")
+			newFunctionManager := kaffeine.NewFunctionManager
+			if dryRun {
+				newFunctionManager = kaffeine.NewReadOnlyFunctionManager
+			}
+			functionManager, err := newFunctionManager("")
+			if err != nil {
+				return err
+			}
 
 			fname := args[len(args)-1]
 			krmFunc, err := functionManager.RemoveFunctionDefinition(fname)
@@ -21,15 +31,31 @@ func NewRemoveCommand() *cobra.Command {
 				return err
 			}
 
+			if dryRun {
+				plan, err := functionManager.Plan()
+				if err != nil {
+					return err
+				}
+
+				fmt.Print(plan)
+				return nil
+			}
+
 			err = functionManager.Save()
 			if err != nil {
 				return err
 			}
 
+			if len(krmFunc.Versions) == 0 {
+				fmt.Fprintf(os.Stderr, "Warning: '%s' could not be resolved, removed it from the config only\n", fname)
+				return nil
+			}
 			fmt.Println("Successfully removed KRM Function '" + krmFunc.GroupName() + "'")
 			return nil
 		},
 	}
 
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print what would change without changing anything")
+
 	return cmd
 }
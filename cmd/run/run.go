@@ -0,0 +1,64 @@
+package run
+
+import (
+	"io"
+	"os"
+
+	"github.com/konveyor/kaffeine/kaffeine"
+
+	"github.com/spf13/cobra"
+)
+
+func NewRunCommand() *cobra.Command {
+	var inputFile string
+	var fnConfigFile string
+	var containerEngine string
+	var skipDigest bool
+
+	cmd := &cobra.Command{
+		Use:   "run [name]",
+		Short: "Runs an installed KRM function against a ResourceList and prints the result",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			functionManager, err := kaffeine.NewReadOnlyFunctionManager("")
+			if err != nil {
+				return err
+			}
+			functionManager.Containers.Engine = containerEngine
+			functionManager.SkipDigestVerification = skipDigest
+
+			var input []byte
+			if inputFile == "" || inputFile == "-" {
+				input, err = io.ReadAll(os.Stdin)
+			} else {
+				input, err = os.ReadFile(inputFile)
+			}
+			if err != nil {
+				return err
+			}
+
+			var fnConfig []byte
+			if fnConfigFile != "" {
+				fnConfig, err = os.ReadFile(fnConfigFile)
+				if err != nil {
+					return err
+				}
+			}
+
+			output, err := functionManager.RunFunction(args[0], input, fnConfig)
+			if err != nil {
+				return err
+			}
+
+			_, err = os.Stdout.Write(output)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "file containing the input ResourceList (defaults to stdin)")
+	cmd.Flags().StringVar(&fnConfigFile, "fn-config", "", "file containing the functionConfig to pass to the function")
+	cmd.Flags().StringVar(&containerEngine, "container-engine", "", "container engine to run container functions with, e.g. docker or podman (defaults to $KAFFEINE_CONTAINER_ENGINE, or whichever is installed)")
+	cmd.Flags().BoolVar(&skipDigest, "insecure-skip-digest", false, "run container images without pinning them to their declared sha256")
+
+	return cmd
+}
@@ -0,0 +1,51 @@
+package runpipeline
+
+import (
+	"io"
+	"os"
+
+	"github.com/konveyor/kaffeine/kaffeine"
+
+	"github.com/spf13/cobra"
+)
+
+func NewRunPipelineCommand() *cobra.Command {
+	var inputFile string
+	var containerEngine string
+
+	cmd := &cobra.Command{
+		Use:   "run-pipeline [name]",
+		Short: "Runs a pipeline from the config against a ResourceList and prints the result",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			functionManager, err := kaffeine.NewReadOnlyFunctionManager("")
+			if err != nil {
+				return err
+			}
+			functionManager.Containers.Engine = containerEngine
+
+			var input []byte
+			if inputFile == "" || inputFile == "-" {
+				input, err = io.ReadAll(os.Stdin)
+			} else {
+				input, err = os.ReadFile(inputFile)
+			}
+			if err != nil {
+				return err
+			}
+
+			output, err := functionManager.RunPipeline(args[0], input)
+			if err != nil {
+				return err
+			}
+
+			_, err = os.Stdout.Write(output)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "file containing the input ResourceList (defaults to stdin)")
+	cmd.Flags().StringVar(&containerEngine, "container-engine", "", "container engine to run container functions with, e.g. docker or podman (defaults to $KAFFEINE_CONTAINER_ENGINE, or whichever is installed)")
+
+	return cmd
+}
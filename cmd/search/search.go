@@ -2,6 +2,7 @@ package search
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/konveyor/kaffeine/kaffeine"
 
@@ -10,23 +11,41 @@ import (
 
 func NewSearchCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "search [name]",
-		Short: "Searches the managed catalogs for a function with the specified name",
+		Use:   "search [query]",
+		Short: "Searches the managed catalogs for functions matching the query",
+		Long: `Searches the managed catalogs for functions matching the query.
+
+Every term of the query has to match. Plain terms are matched against the name,
+tags, publisher, maintainers and description of the functions, tolerating typos
+in the name. Terms like "tag:security" only match a single field, one of:
+` + strings.Join(kaffeine.QueryFields, ", ") + `. Results are sorted by relevance.`,
+		Example: `  kaffeine search logger
+  kaffeine search tag:security publisher:konveyor
+  kaffeine search 'description:"sidecar container"'
+  kaffeine search example.com/Logger@^1.0`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			functionManager := kaffeine.NewFunctionManager("")
+			functionManager, err := kaffeine.NewReadOnlyFunctionManager("")
+			if err != nil {
+				return err
+			}
 
-			fname := args[len(args)-1]
-			res, err := functionManager.SearchFunctionDefintions(fname)
+			fc, err := functionManager.SearchCatalog(strings.Join(args, " "))
 			if err != nil {
 				return err
 			}
 
-			err = functionManager.Save()
+			output, _ := cmd.Flags().GetString("output")
+			if output == "" {
+				output = kaffeine.OutputYaml
+			}
+
+			res, err := kaffeine.Render(output, fc)
 			if err != nil {
 				return err
 			}
 
-			fmt.Println(string(res))
+			fmt.Print(string(res))
 			return nil
 		},
 	}
@@ -10,12 +10,35 @@ import (
 )
 
 func NewUpdateCommand() *cobra.Command {
+	var prerelease bool
+	var skipDigest bool
+	var platform string
+	var dryRun bool
+	var refresh bool
+
 	cmd := &cobra.Command{
 		Use:   "update",
 		Short: "Updates all functions to their latest versions",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			functionManager := kaffeine.NewFunctionManager("")
+			newFunctionManager := kaffeine.NewFunctionManager
+			if dryRun {
+				newFunctionManager = kaffeine.NewReadOnlyFunctionManager
+			}
+			functionManager, err := newFunctionManager("")
+			if err != nil {
+				return err
+			}
+			functionManager.AllowPrerelease = prerelease
+			functionManager.SkipDigestVerification = skipDigest
+			if platform != "" {
+				goos, goarch, err := kaffeine.ParsePlatform(platform)
+				if err != nil {
+					return err
+				}
+				functionManager.Platform = goos + "/" + goarch
+			}
 
+			functionManager.CatMan.ForceRefresh = refresh
 			_, errs := functionManager.CatMan.UpdateAllCatalogs()
 			for _, err := range errs {
 				if err != nil {
@@ -31,7 +54,17 @@ func NewUpdateCommand() *cobra.Command {
 				}
 			}
 
-			err := functionManager.Save()
+			if dryRun {
+				plan, err := functionManager.Plan()
+				if err != nil {
+					return err
+				}
+
+				fmt.Print(plan)
+				return nil
+			}
+
+			err = functionManager.Save()
 			if err != nil {
 				return err
 			}
@@ -41,5 +74,11 @@ func NewUpdateCommand() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().BoolVar(&prerelease, "pre", false, "allow pre-release versions when resolving the latest version")
+	cmd.Flags().BoolVar(&skipDigest, "insecure-skip-digest", false, "do not verify downloaded binaries against the sha256 declared in the catalog")
+	cmd.Flags().StringVar(&platform, "platform", "", "download binaries for the given 'os/arch' instead of the host platform")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print what would change without changing anything")
+	cmd.Flags().BoolVar(&refresh, "refresh", false, "refresh every catalog, even those whose refresh interval did not pass")
+
 	return cmd
 }
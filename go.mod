@@ -3,6 +3,7 @@ module github.com/konveyor/kaffeine
 go 1.18
 
 require (
+	gopkg.in/yaml.v3 v3.0.1
 	k8s.io/apimachinery v0.24.2
 	sigs.k8s.io/yaml v1.3.0
 )
@@ -24,7 +25,7 @@ require (
 	golang.org/x/net v0.0.0-20220127200216-cd36cc0744dd // indirect
 	golang.org/x/text v0.3.7 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
-	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v2 v2.4.0 // indirect
 	k8s.io/klog/v2 v2.60.1 // indirect
 	k8s.io/utils v0.0.0-20220210201930-3a6ce19ff2f9 // indirect
 	sigs.k8s.io/json v0.0.0-20211208200746-9f7c6b3444d2 // indirect
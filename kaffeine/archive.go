@@ -0,0 +1,327 @@
+package kaffeine
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type archiveFormat int
+
+const (
+	archiveNone archiveFormat = iota
+	archiveTar
+	archiveTarGz
+	archiveZip
+)
+
+// Detects the archive format of the file from its first bytes, regardless of
+// its extension
+func detectArchive(file string) (archiveFormat, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return archiveNone, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return archiveNone, err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return archiveTarGz, nil
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")):
+		return archiveZip, nil
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return archiveTar, nil
+	}
+
+	return archiveNone, nil
+}
+
+// Unpacks the downloaded file to dest, which must not exist yet. Archives
+// (tar, tar.gz and zip) are extracted into the directory dest, any other file
+// is taken to be the executable itself and is moved to dest.
+func unpackDownload(download string, dest string) (err error) {
+	format, err := detectArchive(download)
+	if err != nil {
+		return
+	}
+
+	if format == archiveNone {
+		return os.Rename(download, dest)
+	}
+
+	if err = os.Mkdir(dest, 0755); err != nil {
+		return
+	}
+	if format == archiveZip {
+		_, err = extractZip(download, dest)
+	} else {
+		_, err = extractTarFile(download, dest, format == archiveTarGz)
+	}
+	if err != nil {
+		os.RemoveAll(dest)
+		return fmt.Errorf("could not extract archive: %w", err)
+	}
+
+	return nil
+}
+
+// Returns the path of the executable in the unpacked download at dest and
+// makes sure it is executable. For archives, the member named bin is selected.
+func findBinary(dest string, bin string) (binPath string, err error) {
+	info, err := os.Stat(dest)
+	if err != nil {
+		return
+	}
+
+	binPath = dest
+	if info.IsDir() {
+		files, err := listFiles(dest)
+		if err != nil {
+			return "", err
+		}
+		member, err := selectBinary(files, bin)
+		if err != nil {
+			return "", err
+		}
+		binPath = filepath.Join(dest, filepath.FromSlash(member))
+	}
+
+	info, err = os.Stat(binPath)
+	if err != nil {
+		return
+	}
+	if info.Mode().Perm()&0111 != 0111 {
+		err = os.Chmod(binPath, info.Mode().Perm()|0111)
+	}
+	return binPath, err
+}
+
+// Returns the slash-separated paths of the files and symlinks below dir
+func listFiles(dir string) (files []string, err error) {
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		return
+	}
+
+	err = filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return
+}
+
+// Picks the archive member named bin, either by its full path or its base
+// name. If bin is empty, the archive must contain a single file.
+func selectBinary(files []string, bin string) (string, error) {
+	if bin == "" {
+		if len(files) == 1 {
+			return files[0], nil
+		}
+		return "", fmt.Errorf("archive contains %d files and no bin is declared (files: %s)", len(files), strings.Join(files, ", "))
+	}
+
+	bin = strings.TrimPrefix(path.Clean("/"+bin), "/")
+	for _, f := range files {
+		if f == bin {
+			return f, nil
+		}
+	}
+
+	var matches []string
+	for _, f := range files {
+		if path.Base(f) == path.Base(bin) {
+			matches = append(matches, f)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(matches) > 1 {
+		return "", fmt.Errorf("bin '%s' is ambiguous in archive (matches: %s)", bin, strings.Join(matches, ", "))
+	}
+
+	return "", fmt.Errorf("bin '%s' not found in archive (files: %s)", bin, strings.Join(files, ", "))
+}
+
+// Joins the archive member name to dest, refusing names that would end up
+// outside of dest
+func safeJoin(dest string, name string) (string, error) {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("archive member '%s' has an absolute path", name)
+	}
+
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive member '%s' escapes the destination directory", name)
+	}
+
+	return target, nil
+}
+
+// Symlinks may only point further down into the archive, which keeps every
+// path they can be combined into inside of the destination
+func checkSymlink(name string, linkname string) error {
+	if filepath.IsAbs(linkname) || strings.HasPrefix(linkname, "/") {
+		return fmt.Errorf("archive member '%s' links to absolute path '%s'", name, linkname)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(linkname), "/") {
+		if part == ".." {
+			return fmt.Errorf("archive member '%s' links outside of its directory ('%s')", name, linkname)
+		}
+	}
+	return nil
+}
+
+func writeMember(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func extractTarFile(file string, dest string, gzipped bool) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	return extractTar(r, dest)
+}
+
+// Extracts the tar stream into dest. Returns the slash-separated paths of the
+// regular files that were extracted.
+func extractTar(r io.Reader, dest string) (files []string, err error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return files, err
+		}
+
+		target, err := safeJoin(dest, hdr.Name)
+		if err != nil {
+			return files, err
+		}
+		name := filepath.ToSlash(strings.TrimPrefix(target, dest+string(filepath.Separator)))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = writeMember(target, tr, hdr.FileInfo().Mode())
+			files = append(files, name)
+		case tar.TypeSymlink:
+			if err = checkSymlink(hdr.Name, hdr.Linkname); err != nil {
+				return files, err
+			}
+			if err = os.MkdirAll(filepath.Dir(target), 0755); err == nil {
+				err = os.Symlink(hdr.Linkname, target)
+			}
+			files = append(files, name)
+		case tar.TypeLink:
+			var source string
+			if source, err = safeJoin(dest, hdr.Linkname); err != nil {
+				return files, err
+			}
+			if err = os.MkdirAll(filepath.Dir(target), 0755); err == nil {
+				err = os.Link(source, target)
+			}
+			files = append(files, name)
+		default:
+			// Devices, fifos and the like have no place in a function archive
+			continue
+		}
+
+		if err != nil {
+			return files, err
+		}
+	}
+
+	return files, nil
+}
+
+// Extracts the zip archive into dest. Returns the slash-separated paths of the
+// regular files that were extracted.
+func extractZip(file string, dest string) (files []string, err error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		target, err := safeJoin(dest, zf.Name)
+		if err != nil {
+			return files, err
+		}
+
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			err = os.MkdirAll(target, 0755)
+		case mode.IsRegular():
+			var rc io.ReadCloser
+			if rc, err = zf.Open(); err != nil {
+				return files, err
+			}
+			err = writeMember(target, rc, mode)
+			rc.Close()
+			files = append(files, filepath.ToSlash(strings.TrimPrefix(target, dest+string(filepath.Separator))))
+		default:
+			return files, fmt.Errorf("archive member '%s' has unsupported type %v", zf.Name, mode.Type())
+		}
+
+		if err != nil {
+			return files, err
+		}
+	}
+
+	return files, nil
+}
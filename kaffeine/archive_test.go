@@ -0,0 +1,137 @@
+package kaffeine
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testMember struct {
+	name, content, link string
+}
+
+func writeTestArchive(t *testing.T, format archiveFormat, members []testMember) string {
+	t.Helper()
+	var buf bytes.Buffer
+
+	if format == archiveZip {
+		zw := zip.NewWriter(&buf)
+		for _, m := range members {
+			w, err := zw.Create(m.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte(m.content))
+		}
+		zw.Close()
+	} else {
+		var out io.Writer = &buf
+		var gz *gzip.Writer
+		if format == archiveTarGz {
+			gz = gzip.NewWriter(&buf)
+			out = gz
+		}
+		tw := tar.NewWriter(out)
+		for _, m := range members {
+			hdr := &tar.Header{Name: m.name, Mode: 0644, Size: int64(len(m.content)), Typeflag: tar.TypeReg}
+			if m.link != "" {
+				hdr = &tar.Header{Name: m.name, Linkname: m.link, Typeflag: tar.TypeSymlink}
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			tw.Write([]byte(m.content))
+		}
+		tw.Close()
+		if gz != nil {
+			gz.Close()
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "download")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUnpackDownload(t *testing.T) {
+	members := []testMember{
+		{name: "README.md", content: "readme"},
+		{name: "bin/foo-amd64-linux", content: "#!/bin/sh\ncat\n"},
+		{name: "bin/foo", link: "foo-amd64-linux"},
+	}
+
+	var tests = []struct {
+		name    string
+		format  archiveFormat
+		members []testMember
+		bin     string
+		want    string
+		wantErr bool
+	}{
+		{"tar full path", archiveTar, members, "bin/foo-amd64-linux", "bin/foo-amd64-linux", false},
+		{"tar base name", archiveTar, members, "foo-amd64-linux", "bin/foo-amd64-linux", false},
+		{"tar.gz", archiveTarGz, members, "foo-amd64-linux", "bin/foo-amd64-linux", false},
+		{"zip", archiveZip, members[:2], "foo-amd64-linux", "bin/foo-amd64-linux", false},
+		{"symlink", archiveTar, members, "bin/foo", "bin/foo", false},
+		{"single file without bin", archiveTar, members[1:2], "", "bin/foo-amd64-linux", false},
+		{"missing bin", archiveTar, members, "bar", "", true},
+		{"several files without bin", archiveTar, members, "", "", true},
+		{"tar traversal", archiveTar, []testMember{{name: "../../evil", content: "x"}}, "evil", "", true},
+		{"zip traversal", archiveZip, []testMember{{name: "../evil", content: "x"}}, "evil", "", true},
+		{"absolute path", archiveTar, []testMember{{name: "/tmp/evil", content: "x"}}, "evil", "", true},
+		{"escaping symlink", archiveTar, []testMember{{name: "evil", link: "../../etc/passwd"}}, "evil", "", true},
+		{"absolute symlink", archiveTar, []testMember{{name: "evil", link: "/etc/passwd"}}, "evil", "", true},
+	}
+
+	for _, test := range tests {
+		download := writeTestArchive(t, test.format, test.members)
+		dest := filepath.Join(t.TempDir(), "dest")
+
+		binPath, err := "", unpackDownload(download, dest)
+		if err == nil {
+			binPath, err = findBinary(dest, test.bin)
+		}
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name, err, test.wantErr)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+
+		if binPath != filepath.Join(dest, test.want) {
+			t.Errorf("%s: got %s, want %s", test.name, binPath, test.want)
+		}
+		info, err := os.Stat(binPath)
+		if err != nil || info.Mode().Perm()&0111 == 0 {
+			t.Errorf("%s: binary is not executable (%v)", test.name, err)
+		}
+	}
+}
+
+func TestUnpackDownloadPlain(t *testing.T) {
+	download := filepath.Join(t.TempDir(), "download")
+	os.WriteFile(download, []byte("#!/bin/sh\ncat\n"), 0644)
+	dest := filepath.Join(t.TempDir(), "dest")
+
+	if err := unpackDownload(download, dest); err != nil {
+		t.Fatal(err)
+	}
+	binPath, err := findBinary(dest, "foo-amd64-linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binPath != dest {
+		t.Errorf("got %s, want %s", binPath, dest)
+	}
+	if info, err := os.Stat(binPath); err != nil || info.Mode().Perm()&0111 == 0 {
+		t.Errorf("binary is not executable (%v)", err)
+	}
+}
@@ -0,0 +1,192 @@
+package kaffeine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A catalog managed by kaffeine, as listed in the config
+type CatalogConfig struct {
+	// Short name to refer to the catalog by, as in "name::group/name"
+	Name string `json:"name"`
+	Uri  string `json:"uri"`
+	// Catalogs with lower values take precedence when they define the same
+	// function. Catalogs with the same priority keep the order they are listed
+	// in.
+	Priority int `json:"priority,omitempty"`
+	// Disabled catalogs stay in the config, but are not loaded
+	Enabled *bool `json:"enabled,omitempty"`
+	// Key of the credentials to fetch the catalog with, see Config.Credentials
+	Auth string `json:"auth,omitempty"`
+	// Minimum time between two refreshes of the catalog by update, as a
+	// duration like "24h". Empty refreshes it on every update.
+	Refresh string `json:"refresh,omitempty"`
+}
+
+var catalogNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]*[a-z0-9])?$`)
+
+// Older configs list catalogs as bare URIs. They are read as catalogs without
+// a name, which is derived from the URI when the config is read.
+func (cc *CatalogConfig) UnmarshalJSON(data []byte) error {
+	var uri string
+	if err := json.Unmarshal(data, &uri); err == nil {
+		*cc = CatalogConfig{Uri: uri}
+		return nil
+	}
+
+	type plain CatalogConfig
+	return json.Unmarshal(data, (*plain)(cc))
+}
+
+func (cc CatalogConfig) IsEnabled() bool {
+	return cc.Enabled == nil || *cc.Enabled
+}
+
+// Returns the parsed Refresh, 0 if the catalog is refreshed on every update
+func (cc CatalogConfig) RefreshInterval() (time.Duration, error) {
+	if cc.Refresh == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(cc.Refresh)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid refresh interval '%s' of catalog '%s'", cc.Refresh, cc.Name)
+	}
+	return d, nil
+}
+
+// Checks the name, uri and refresh interval of the catalog
+func (cc CatalogConfig) Validate() error {
+	if !catalogNamePattern.MatchString(cc.Name) {
+		return fmt.Errorf("invalid catalog name '%s' (use lowercase letters, digits, '.', '_' and '-')", cc.Name)
+	}
+	if u, err := url.ParseRequestURI(cc.Uri); err != nil {
+		return fmt.Errorf("invalid uri of catalog '%s': %w", cc.Name, err)
+	} else if u.Scheme == "oci" {
+		if _, err := ParseOCIReference(cc.Uri); err != nil {
+			return err
+		}
+	} else if isGitScheme(u.Scheme) {
+		if _, err := ParseGitReference(cc.Uri); err != nil {
+			return err
+		}
+	}
+	_, err := cc.RefreshInterval()
+	return err
+}
+
+func (cc CatalogConfig) String() string {
+	settings := []string{}
+	if cc.Priority != 0 {
+		settings = append(settings, fmt.Sprintf("priority %d", cc.Priority))
+	}
+	if !cc.IsEnabled() {
+		settings = append(settings, "disabled")
+	}
+	if cc.Auth != "" {
+		settings = append(settings, "auth "+cc.Auth)
+	}
+	if cc.Refresh != "" {
+		settings = append(settings, "refresh "+cc.Refresh)
+	}
+
+	s := cc.Name + " " + cc.Uri
+	if len(settings) > 0 {
+		s += " (" + strings.Join(settings, ", ") + ")"
+	}
+	return s
+}
+
+// Derives a catalog name from the file name in the uri, such as "konveyor"
+// for "https://example.com/catalogs/konveyor.yaml", or from the repository of
+// an oci:// uri
+func CatalogNameFromUri(uri string) string {
+	name := ""
+	if ref, err := ParseOCIReference(uri); err == nil {
+		name = path.Base(ref.Repository)
+	} else if u, err := url.Parse(uri); err == nil {
+		name = strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+		if name == "" || name == "." || name == "/" {
+			name = u.Host
+		}
+	}
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, name)
+	name = strings.Trim(name, ".-_")
+
+	if name == "" {
+		return "catalog"
+	}
+	return name
+}
+
+// Names the catalogs without a name after their URI, and renames those whose
+// name is taken by an earlier one by adding a number
+func nameCatalogs(catalogs []CatalogConfig) {
+	taken := map[string]bool{}
+	for i := range catalogs {
+		name := catalogs[i].Name
+		if name == "" {
+			name = CatalogNameFromUri(catalogs[i].Uri)
+		}
+
+		unique := name
+		for n := 2; taken[unique]; n++ {
+			unique = fmt.Sprintf("%s-%d", name, n)
+		}
+		catalogs[i].Name = unique
+		taken[unique] = true
+	}
+}
+
+// Returns the index of the catalog with the given name or uri, -1 if none
+func (c *Config) catalogIndex(nameOrUri string) int {
+	for i, cc := range c.Catalogs {
+		if cc.Name == nameOrUri || cc.Uri == nameOrUri {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the catalog with the given name or uri
+func (c *Config) GetCatalog(nameOrUri string) (*CatalogConfig, error) {
+	i := c.catalogIndex(nameOrUri)
+	if i < 0 {
+		return nil, fmt.Errorf("catalog '%s' not present in '%s'", nameOrUri, c.FilePath)
+	}
+	return &c.Catalogs[i], nil
+}
+
+// Returns the URIs of the enabled catalogs, highest priority first
+func (c *Config) CatalogUris() []string {
+	catalogs := []CatalogConfig{}
+	for _, cc := range c.Catalogs {
+		if cc.IsEnabled() {
+			catalogs = append(catalogs, cc)
+		}
+	}
+	sort.SliceStable(catalogs, func(i, j int) bool {
+		return catalogs[i].Priority < catalogs[j].Priority
+	})
+
+	uris := []string{}
+	for _, cc := range catalogs {
+		uris = append(uris, cc.Uri)
+	}
+	return uris
+}
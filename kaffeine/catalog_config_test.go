@@ -0,0 +1,149 @@
+package kaffeine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCatalogConfigMigration(t *testing.T) {
+	t.Setenv("KAFFEINE_GLOBAL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	dir := t.TempDir()
+	config := `catalogs:
+- https://example.com/catalogs/Konveyor.yaml
+- https://mirror.example.com/catalogs/konveyor.yaml
+- name: tools
+  uri: file:///tools.yaml
+  priority: -1
+- name: old
+  uri: file:///old.yaml
+  enabled: false
+`
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := MakeConfig(dir)
+	names := []string{}
+	for _, cc := range c.Catalogs {
+		names = append(names, cc.Name)
+	}
+	if want := "[konveyor konveyor-2 tools old]"; fmt.Sprint(names) != want {
+		t.Errorf("got names %v, want %s", names, want)
+	}
+	if want := "[file:///tools.yaml https://example.com/catalogs/Konveyor.yaml https://mirror.example.com/catalogs/konveyor.yaml]"; fmt.Sprint(c.CatalogUris()) != want {
+		t.Errorf("got uris %v, want %s", c.CatalogUris(), want)
+	}
+	if cc, err := c.GetCatalog("konveyor-2"); err != nil || cc.Uri != "https://mirror.example.com/catalogs/konveyor.yaml" {
+		t.Errorf("got %v (%v), want the mirror", cc, err)
+	}
+
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(filepath.Join(dir, "config.yaml"))
+	if !strings.Contains(string(b), "name: konveyor-2\n  uri: https://mirror.example.com/catalogs/konveyor.yaml") {
+		t.Errorf("catalogs not migrated, got:\n%s", b)
+	}
+}
+
+func TestAddCatalogConfig(t *testing.T) {
+	var tests = []struct {
+		cc   CatalogConfig
+		name string
+		err  bool
+	}{
+		{CatalogConfig{Uri: "https://example.com/new.yaml"}, "new", false},
+		{CatalogConfig{Uri: "https://example.org/tools.yaml"}, "tools-2", false},
+		{CatalogConfig{Uri: "https://example.com/"}, "example.com", false},
+		{CatalogConfig{Uri: "oci://ghcr.io/konveyor/functions:v1"}, "functions", false},
+		{CatalogConfig{Uri: "oci://ghcr.io/"}, "", true},
+		{CatalogConfig{Name: "custom", Uri: "https://example.com/x.yaml", Refresh: "12h"}, "custom", false},
+		{CatalogConfig{Uri: "file:///tools.yaml"}, "", true},
+		{CatalogConfig{Name: "tools", Uri: "https://example.com/y.yaml"}, "", true},
+		{CatalogConfig{Name: "Bad Name", Uri: "https://example.com/y.yaml"}, "", true},
+		{CatalogConfig{Uri: "https://example.com/y.yaml", Refresh: "daily"}, "", true},
+	}
+
+	for _, test := range tests {
+		c := Config{Catalogs: []CatalogConfig{{Name: "tools", Uri: "file:///tools.yaml"}}}
+		err := c.AddCatalog(test.cc)
+		if (err != nil) != test.err {
+			t.Errorf("%v: got error %v", test.cc, err)
+			continue
+		}
+		if err == nil && c.Catalogs[1].Name != test.name {
+			t.Errorf("%v: got name %s, want %s", test.cc, c.Catalogs[1].Name, test.name)
+		}
+	}
+}
+
+func TestNamedCatalogCache(t *testing.T) {
+	dir := t.TempDir()
+	uri := "file:///test.yaml"
+
+	cm := MakeCatalogManager(dir)
+	if err := cm.AddCatalogFromStruct(uri, makeTestCatalog("test", "Test", "v1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "catalogs", legacyCacheFile(uri))); err != nil {
+		t.Errorf("catalog without a name not cached by hash: %v", err)
+	}
+
+	// Catalogs cached before they had a name are still found
+	cm = MakeCatalogManager(dir)
+	cm.Settings[uri] = CatalogConfig{Name: "test", Uri: uri}
+	if _, err := cm.GetCachedCatalog(uri); err != nil {
+		t.Errorf("legacy cache not read: %v", err)
+	}
+	if err := cm.AddCatalogFromUri(uri); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.Save(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "catalogs", "test.yaml"))
+	if err != nil || !strings.HasPrefix(string(b), cacheHeader(uri)) {
+		t.Errorf("catalog not cached by name, got %q (%v)", b, err)
+	}
+
+	// The cache of a name now used by another uri is not read
+	cm = MakeCatalogManager(dir)
+	cm.Settings["file:///other.yaml"] = CatalogConfig{Name: "test", Uri: "file:///other.yaml"}
+	if _, err := cm.GetCachedCatalog("file:///other.yaml"); err == nil {
+		t.Errorf("read the cache of %s for another uri", uri)
+	}
+}
+
+func TestCatalogRefreshDue(t *testing.T) {
+	var tests = []struct {
+		refresh   string
+		refreshed time.Duration
+		force     bool
+		due       bool
+	}{
+		{"", time.Minute, false, true},
+		{"24h", time.Hour, false, false},
+		{"24h", 25 * time.Hour, false, true},
+		{"24h", time.Hour, true, true},
+		{"24h", 0, false, true},
+	}
+
+	for _, test := range tests {
+		cm := MakeCatalogManager(t.TempDir())
+		cm.Settings["file:///test.yaml"] = CatalogConfig{Name: "test", Uri: "file:///test.yaml", Refresh: test.refresh}
+		if test.refreshed != 0 {
+			cm.State["file:///test.yaml"] = CatalogState{Refreshed: time.Now().Add(-test.refreshed)}
+		}
+		cm.ForceRefresh = test.force
+		if got := cm.refreshDue("file:///test.yaml"); got != test.due {
+			t.Errorf("%+v: got due %v", test, got)
+		}
+	}
+}
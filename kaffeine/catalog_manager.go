@@ -1,7 +1,9 @@
 package kaffeine
 
 import (
+	"bytes"
 	"crypto/sha1"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
@@ -9,8 +11,11 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	"golang.org/x/exp/slices"
 	"sigs.k8s.io/yaml"
 )
 
@@ -23,29 +28,119 @@ type CatalogManager struct {
 	Catalogs map[string]FunctionCatalog
 
 	// Every function contained within each catalog. The key is the
-	// FunctionDefinition's GroupName (group + "/" + name). As a result, an effort
-	// is made to ensure that every new catalog added has unique functions inside
+	// FunctionDefinition's GroupName (group + "/" + name). Functions found in
+	// several catalogs are merged: the fields come from the catalog with the
+	// highest priority, and versions are combined, see Priority.
 	Functions map[string]FunctionDefinition
+
+	// URIs of the catalogs, highest priority first. Catalogs are added with
+	// the lowest priority.
+	Priority []string
+
+	// Catalogs that could not be loaded, with the reason. They are retried on
+	// update and stay in the config until removed.
+	Unavailable map[string]error
+
+	// Refuse catalogs with problems instead of warning about them. See
+	// ValidateCatalog
+	Strict bool
+
+	// The settings of the catalogs from the config, keyed by URI. Catalogs
+	// without settings are cached by the hash of their URI and can only be
+	// referred to by URI.
+	Settings map[string]CatalogConfig
+
+	// When each catalog was last fetched and how to revalidate it, keyed by
+	// URI
+	State map[string]CatalogState
+
+	// Refresh every catalog on update, even those refreshed recently
+	ForceRefresh bool
+
+	// The client HTTP(S) catalogs are fetched with
+	Client *HTTPClient
+}
+
+// What is known about the cached copy of a catalog
+type CatalogState struct {
+	// When the catalog was last fetched, see CatalogConfig.Refresh
+	Refreshed time.Time `json:"refreshed"`
+	// Validators of the last response, sent back on update so that the server
+	// can answer that the catalog did not change. For oci:// catalogs, ETag is
+	// the digest of the manifest.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	// The commit git catalogs are pinned to. It only moves on update.
+	Commit string `json:"commit,omitempty"`
 }
 
+// The file in the catalogs directory that State is saved to. Catalog names
+// can not start with a dot, so it never clashes with a cached catalog.
+const stateFile = ".state.yaml"
+
+// Returned by fetchCatalog when the server answers that the catalog did not
+// change
+var errCatalogNotModified = errors.New("catalog not modified")
+
 // Creates a CatalogManager struct
 func MakeCatalogManager(directory string) (cm CatalogManager) {
 	cm.Directory = filepath.Clean(filepath.Join(directory, "/catalogs"))
 	cm.Catalogs = map[string]FunctionCatalog{}
 	cm.Functions = map[string]FunctionDefinition{}
+	cm.Unavailable = map[string]error{}
+	cm.Settings = map[string]CatalogConfig{}
+	cm.State = map[string]CatalogState{}
 
-	os.MkdirAll(cm.Directory, os.ModePerm)
+	if b, err := os.ReadFile(filepath.Join(cm.Directory, stateFile)); err == nil {
+		yaml.Unmarshal(b, &cm.State)
+	}
 
 	return cm
 }
 
+// Returns the name of the cache file of the catalog: its name from Settings,
+// or the SHA-1 of its uri
+func (cm *CatalogManager) cacheFile(uri string) string {
+	if name := cm.Settings[uri].Name; name != "" {
+		return name + ".yaml"
+	}
+	return legacyCacheFile(uri)
+}
+
+// Catalogs used to be cached by the SHA-1 of their uri only
+func legacyCacheFile(uri string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(uri))) + ".yaml"
+}
+
+// Named cache files start with a comment holding the uri, so that a catalog
+// whose uri changed in the config is not read from the old one's cache
+func cacheHeader(uri string) string {
+	return "# " + uri + "\n"
+}
+
+// Reads the cached copy of the catalog, from before it had a name if needed
+func (cm *CatalogManager) readCache(uri string) ([]byte, error) {
+	if file := cm.cacheFile(uri); file != legacyCacheFile(uri) {
+		b, err := os.ReadFile(filepath.Join(cm.Directory, file))
+		if err == nil && bytes.HasPrefix(b, []byte(cacheHeader(uri))) {
+			return b[len(cacheHeader(uri)):], nil
+		}
+	}
+
+	b, err := os.ReadFile(filepath.Join(cm.Directory, legacyCacheFile(uri)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("cached catalog '%s' not present in filesystem", uri)
+	}
+	return b, err
+}
+
 // Moves all current cached catalogs into "../catalogs.bak", then tries to save
 // all new catalogs into "catalogs".
 func (cm *CatalogManager) Save() (err error) {
 	bakDirectory := filepath.Clean(filepath.Join(cm.Directory, "../catalogs.bak"))
 	os.RemoveAll(bakDirectory)
 	err = os.Rename(cm.Directory, bakDirectory)
-	if err != nil {
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
 
@@ -54,14 +149,36 @@ func (cm *CatalogManager) Save() (err error) {
 		return err
 	}
 
+	// Clones of git catalogs are kept to only fetch what is new on update
+	os.Rename(filepath.Join(bakDirectory, gitReposDirectory), filepath.Join(cm.Directory, gitReposDirectory))
+	cm.pruneGitRepos()
+
+	state := map[string]CatalogState{}
 	for uri, cat := range cm.Catalogs {
 		b, err := yaml.Marshal(cat)
 		if err != nil {
 			return err
 		}
 
-		hashName := fmt.Sprintf("%x", sha1.Sum([]byte(uri))) + ".yaml"
-		err = os.WriteFile(filepath.Join(cm.Directory, hashName), b, os.ModePerm)
+		if file := cm.cacheFile(uri); file != legacyCacheFile(uri) {
+			b = append([]byte(cacheHeader(uri)), b...)
+		}
+		err = os.WriteFile(filepath.Join(cm.Directory, cm.cacheFile(uri)), b, os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		if s, ok := cm.State[uri]; ok {
+			state[uri] = s
+		}
+	}
+
+	if len(state) > 0 {
+		b, err := yaml.Marshal(state)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(cm.Directory, stateFile), b, os.ModePerm)
 		if err != nil {
 			return err
 		}
@@ -72,27 +189,77 @@ func (cm *CatalogManager) Save() (err error) {
 	return
 }
 
-// Adds the given FunctionCatalog to the catalog manager. Throws errors if the
-// catalog:
-// 	- has functions with GroupNames already present
-// 	- has functions with no versions
+// Adds the given FunctionCatalog to the catalog manager. Functions that are
+// already in other catalogs are merged, see Functions. Throws errors if the
+// catalog has functions with no versions.
 func (cm *CatalogManager) AddCatalogFromStruct(uri string, cat FunctionCatalog) (err error) {
-	// Check for conflicting names
+	if err := checkCatalogFunctions(cat); err != nil {
+		return err
+	}
+
+	cm.Catalogs[uri] = cat
+	delete(cm.Unavailable, uri)
+	if !slices.Contains(cm.Priority, uri) {
+		cm.Priority = append(cm.Priority, uri)
+	}
+	cm.index()
+
+	return nil
+}
+
+func checkCatalogFunctions(cat FunctionCatalog) error {
 	for _, fn := range cat.Spec.KrmFunctions {
-		if _, ok := cm.Functions[fn.GroupName()]; ok {
-			return errors.New("attempted to add catalog that contains conflicting names")
-		}
 		if len(fn.Versions) == 0 {
 			return fmt.Errorf("attempted to add function '%s' with no versions", fn.GroupName())
 		}
 	}
+	return nil
+}
 
-	for _, fn := range cat.Spec.KrmFunctions {
-		cm.Functions[fn.GroupName()] = fn
+// Rebuilds Functions from the catalogs, in order of priority
+func (cm *CatalogManager) index() {
+	cm.Functions = map[string]FunctionDefinition{}
+	for _, uri := range cm.Priority {
+		cat, ok := cm.Catalogs[uri]
+		if !ok {
+			continue
+		}
+
+		for _, fn := range cat.Spec.KrmFunctions {
+			merged, ok := cm.Functions[fn.GroupName()]
+			if !ok {
+				fn.Versions = append([]FunctionVersion{}, fn.Versions...)
+				cm.Functions[fn.GroupName()] = fn
+				continue
+			}
+
+			for _, v := range fn.Versions {
+				if _, err := merged.GetVersion(v.Name); err != nil {
+					merged.Versions = append(merged.Versions, v)
+				}
+			}
+			cm.Functions[fn.GroupName()] = merged
+		}
 	}
-	cm.Catalogs[uri] = cat
+}
 
-	return nil
+// Orders Priority like the given uris, see Config.CatalogUris. Catalogs that
+// are not listed keep their order after the listed ones.
+func (cm *CatalogManager) Reorder(uris []string) {
+	priority := []string{}
+	for _, uri := range uris {
+		if slices.Contains(cm.Priority, uri) && !slices.Contains(priority, uri) {
+			priority = append(priority, uri)
+		}
+	}
+	for _, uri := range cm.Priority {
+		if !slices.Contains(priority, uri) {
+			priority = append(priority, uri)
+		}
+	}
+
+	cm.Priority = priority
+	cm.index()
 }
 
 // Adds the catalog with the given uri to the catalog manager. Throws errors if the
@@ -108,12 +275,21 @@ func (cm *CatalogManager) AddCatalogFromUri(uri string) (err error) {
 
 	cat, err = cm.GetCachedCatalog(uri)
 	if err != nil {
-		cat, err = cm.GetExternalCatalog(uri)
-
-		// Fetch externally
+		// Fetch externally. Git catalogs are read at their pinned commit.
+		var state CatalogState
+		if commit := cm.State[uri].Commit; commit != "" {
+			cat, state, err = cm.fetchGitCatalog(uri, commit)
+		} else {
+			cat, state, err = cm.fetchCatalog(uri, CatalogState{})
+		}
 		if err != nil {
 			return err
 		}
+		if err = cm.AddCatalogFromStruct(uri, cat); err != nil {
+			return err
+		}
+		cm.State[uri] = state
+		return nil
 	}
 
 	return cm.AddCatalogFromStruct(uri, cat)
@@ -121,25 +297,7 @@ func (cm *CatalogManager) AddCatalogFromUri(uri string) (err error) {
 
 // Tries to fetch the catalog with the given uri from the filesystem cache.
 func (cm *CatalogManager) GetCachedCatalog(uri string) (fc FunctionCatalog, err error) {
-	catalogFileInfo, err := os.ReadDir(cm.Directory)
-	if err != nil {
-		return
-	}
-
-	hashedFilename := fmt.Sprintf("%x", sha1.Sum([]byte(uri))) + ".yaml"
-	ok := false
-	for _, x := range catalogFileInfo {
-		if x.Name() == hashedFilename {
-			ok = true
-			break
-		}
-	}
-
-	if !ok {
-		return fc, fmt.Errorf("cached catalog '%s' (hash '%s') not present in filesystem", uri, hashedFilename)
-	}
-
-	data, err := os.ReadFile(filepath.Join(cm.Directory, hashedFilename))
+	data, err := cm.readCache(uri)
 	if err != nil {
 		return
 	}
@@ -155,22 +313,65 @@ func (cm *CatalogManager) GetCachedCatalog(uri string) (fc FunctionCatalog, err
 // Tries to fetch the catalog from the given uri. External in this case means
 // "not from the filesystem cache"
 func (cm *CatalogManager) GetExternalCatalog(uri string) (fc FunctionCatalog, err error) {
+	fc, _, err = cm.fetchCatalog(uri, CatalogState{})
+	return
+}
+
+// Fetches the catalog like GetExternalCatalog. The validators of prev are sent
+// along, and errCatalogNotModified is returned if the server answers that the
+// catalog did not change. Returns the state of the fetched catalog.
+func (cm *CatalogManager) fetchCatalog(uri string, prev CatalogState) (fc FunctionCatalog, state CatalogState, err error) {
 	u, err := url.ParseRequestURI(uri)
 	if err != nil {
 		return
 	}
 
 	var data []byte
+	state.Refreshed = time.Now()
 
-	if u.Scheme == "file" {
+	switch u.Scheme {
+	case "file":
 		data, err = os.ReadFile(u.Path)
-	} else {
+	case "git+https", "git+http", "git+file":
+		fc, state, err = cm.fetchGitCatalog(uri, "")
+		if err == nil && state.Commit == prev.Commit {
+			return fc, state, errCatalogNotModified
+		}
+		return
+	case "oci":
+		// The digest of the manifest tells whether the catalog changed
+		var body io.ReadCloser
+		body, state.ETag, err = cm.Client.OpenOCI(uri, cm.Settings[uri].Auth, prev.ETag)
+		if err != nil {
+			return
+		}
+		if body == nil {
+			prev.Refreshed = state.Refreshed
+			return fc, prev, errCatalogNotModified
+		}
+		defer body.Close()
+		data, err = io.ReadAll(body)
+	default:
+		header := http.Header{}
+		if prev.ETag != "" {
+			header.Set("If-None-Match", prev.ETag)
+		}
+		if prev.LastModified != "" {
+			header.Set("If-Modified-Since", prev.LastModified)
+		}
+
 		var resp *http.Response
-		resp, err = http.Get(uri)
+		resp, err = cm.Client.Get(uri, cm.Settings[uri].Auth, header)
 		if err != nil {
 			return
 		}
 		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotModified {
+			prev.Refreshed = state.Refreshed
+			return fc, prev, errCatalogNotModified
+		}
+		state.ETag = resp.Header.Get("ETag")
+		state.LastModified = resp.Header.Get("Last-Modified")
 		data, err = io.ReadAll(resp.Body)
 	}
 
@@ -178,85 +379,239 @@ func (cm *CatalogManager) GetExternalCatalog(uri string) (fc FunctionCatalog, er
 		return
 	}
 
-	err = yaml.Unmarshal(data, &fc)
+	fc, err = cm.parseCatalog(uri, data)
+	return
+}
+
+// Fetches the git catalog like fetchCatalog, at the given commit or at the
+// latest commit of its ref if empty
+func (cm *CatalogManager) fetchGitCatalog(uri string, commit string) (fc FunctionCatalog, state CatalogState, err error) {
+	var data []byte
+	state.Refreshed = time.Now()
+	data, state.Commit, err = cm.readGitCatalog(uri, commit)
 	if err != nil {
 		return
 	}
 
+	fc, err = cm.parseCatalog(uri, data)
+	return
+}
+
+// Validates and unmarshals the fetched catalog, see Strict
+func (cm *CatalogManager) parseCatalog(uri string, data []byte) (fc FunctionCatalog, err error) {
+	if problems := ValidateCatalog(data); len(problems) > 0 {
+		invalid := &InvalidCatalogError{Uri: uri, Problems: problems}
+		if cm.Strict {
+			return fc, invalid
+		}
+		fmt.Fprintf(os.Stderr, "warning: %v\n", invalid)
+	}
+
+	err = yaml.Unmarshal(data, &fc)
 	return
 }
 
 // Tries to remove the catalog from the CatalogManager
 func (cm *CatalogManager) RemoveCatalog(uri string) (oldFc FunctionCatalog, err error) {
+	if i := slices.Index(cm.Priority, uri); i >= 0 {
+		cm.Priority = slices.Delete(cm.Priority, i, i+1)
+	}
+	if _, ok := cm.Unavailable[uri]; ok {
+		delete(cm.Unavailable, uri)
+		return oldFc, nil
+	}
 	if _, ok := cm.Catalogs[uri]; !ok {
 		return oldFc, errors.New("catalog with uri not present")
 	}
 
-	for _, fn := range cm.Catalogs[uri].Spec.KrmFunctions {
-		delete(cm.Functions, fn.GroupName())
-	}
-
 	oldFc = cm.Catalogs[uri]
 	delete(cm.Catalogs, uri)
+	cm.index()
 
 	return oldFc, nil
 }
 
-// Updates the catalog with the given uri. This is accomplished by deleting the
+// Updates the catalog with the given uri, keeping its priority. The old
+// catalog is kept if the new one can not be fetched.
 func (cm *CatalogManager) UpdateCatalog(uri string) (oldFc FunctionCatalog, err error) {
-	oldFc, err = cm.RemoveCatalog(uri)
-	if err != nil {
-		return
+	oldFc, ok := cm.Catalogs[uri]
+	if !ok {
+		return oldFc, errors.New("catalog with uri not present")
 	}
 
-	var newFc FunctionCatalog
-	newFc, err = cm.GetExternalCatalog(uri)
+	newFc, state, err := cm.fetchCatalog(uri, cm.State[uri])
+	if errors.Is(err, errCatalogNotModified) {
+		cm.State[uri] = state
+		return oldFc, nil
+	}
 	if err != nil {
-		cm.AddCatalogFromStruct(uri, oldFc)
 		return
 	}
-
-	// Check for conflicting names
-	for _, fn := range newFc.Spec.KrmFunctions {
-		if _, ok := cm.Functions[fn.GroupName()]; ok {
-			cm.AddCatalogFromStruct(uri, oldFc)
-			return oldFc, errors.New("attempted to add catalog that contains conflicting names")
-		}
-		if len(fn.Versions) == 0 {
-			cm.AddCatalogFromStruct(uri, oldFc)
-			return oldFc, fmt.Errorf("attempted to add function '%s' with no versions", fn.GroupName())
-		}
+	if err = checkCatalogFunctions(newFc); err != nil {
+		return
 	}
 
-	for _, fn := range newFc.Spec.KrmFunctions {
-		cm.Functions[fn.GroupName()] = fn
-	}
 	cm.Catalogs[uri] = newFc
+	cm.State[uri] = state
+	cm.index()
 
 	return
 }
 
-// Executes UpdateCatalog on all catalogs in the struct. Returns an array of old
-// catalogs and an array of errors encountered.
+// Reports whether the refresh interval of the catalog passed since it was last
+// fetched, see CatalogConfig.Refresh
+func (cm *CatalogManager) refreshDue(uri string) bool {
+	interval, err := cm.Settings[uri].RefreshInterval()
+	if cm.ForceRefresh || err != nil || interval == 0 {
+		return true
+	}
+
+	state, ok := cm.State[uri]
+	return !ok || time.Since(state.Refreshed) >= interval
+}
+
+// Executes UpdateCatalog on all catalogs in the struct whose refresh interval
+// passed. Returns an array of old catalogs and an array of errors encountered.
 func (cm *CatalogManager) UpdateAllCatalogs() (oldFcs []FunctionCatalog, errs []error) {
 	for uri, _ := range cm.Catalogs {
+		if !cm.refreshDue(uri) {
+			continue
+		}
 		fc, err := cm.UpdateCatalog(uri)
 		oldFcs = append(oldFcs, fc)
 		errs = append(errs, err)
 	}
 
+	for uri := range cm.Unavailable {
+		fc, state, err := cm.fetchCatalog(uri, CatalogState{})
+		if err == nil {
+			err = cm.AddCatalogFromStruct(uri, fc)
+		}
+		if err == nil {
+			cm.State[uri] = state
+		}
+		if err != nil {
+			cm.Unavailable[uri] = err
+		}
+		oldFcs = append(oldFcs, FunctionCatalog{})
+		errs = append(errs, err)
+	}
+
 	return
 }
 
-// Searches for the given function
+// Returns the uri of the catalog with the highest priority that contains the
+// function with the given GroupName
+func (cm *CatalogManager) FindCatalog(groupName string) (uri string, ok bool) {
+	return cm.FindVersionCatalog(groupName, "")
+}
+
+// Returns the uri of the catalog with the highest priority that contains the
+// given version of the function, or any version if version is ""
+func (cm *CatalogManager) FindVersionCatalog(groupName string, version string) (uri string, ok bool) {
+	for _, uri := range cm.Priority {
+		for _, fn := range cm.Catalogs[uri].Spec.KrmFunctions {
+			if fn.GroupName() != groupName {
+				continue
+			}
+			if _, err := fn.GetVersion(version); version == "" || err == nil {
+				return uri, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Returns the uri of the catalog with the given alias: its uri, its name in
+// the config, or the name in its metadata
+func (cm *CatalogManager) ResolveCatalogAlias(alias string) (uri string, err error) {
+	if _, ok := cm.Catalogs[alias]; ok {
+		return alias, nil
+	}
+	for _, uri := range cm.Priority {
+		if _, ok := cm.Catalogs[uri]; ok && cm.Settings[uri].Name == alias {
+			return uri, nil
+		}
+	}
+
+	matches := []string{}
+	for _, uri := range cm.Priority {
+		if cat, ok := cm.Catalogs[uri]; ok && cat.Metadata != nil && cat.Metadata.Name == alias {
+			matches = append(matches, uri)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no catalog named '%s'", alias)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("catalog name '%s' is ambiguous, use one of %s", alias, strings.Join(matches, ", "))
+}
+
+// Returns the functions of the catalog with the given alias, or the merged
+// functions of all catalogs if alias is ""
+func (cm *CatalogManager) catalogFunctions(alias string) (map[string]FunctionDefinition, error) {
+	if alias == "" {
+		return cm.Functions, nil
+	}
+
+	uri, err := cm.ResolveCatalogAlias(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	fns := map[string]FunctionDefinition{}
+	for _, fn := range cm.Catalogs[uri].Spec.KrmFunctions {
+		fns[fn.GroupName()] = fn
+	}
+	return fns, nil
+}
+
+// Returns the sha256 of the content of the catalog with the given uri
+func (cm *CatalogManager) Hash(uri string) (string, error) {
+	cat, ok := cm.Catalogs[uri]
+	if !ok {
+		return "", fmt.Errorf("catalog '%s' not present", uri)
+	}
+
+	b, err := yaml.Marshal(cat)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(b)), nil
+}
+
+// Searches for the functions whose GroupName contains the given name, sorted by
+// GroupName. A name like "alias::group/name" only searches the catalog with
+// that alias, see ResolveCatalogAlias. A version range keeps every version
+// that satisfies it, pre-releases included, leaving it to the caller to pick
+// one. See Query for searching the other fields.
 func (cm *CatalogManager) Search(fname string, lowercase bool) (fns []FunctionDefinition, err error) {
+	alias, _ := SplitCatalogAlias(fname)
+	functions, err := cm.catalogFunctions(alias)
+	if err != nil {
+		return nil, err
+	}
+
 	group, name, version := ToGroupNameVersion(fname)
 	groupName := name
 	if group != "" {
 		groupName = group + "/" + groupName
 	}
 
-	for _, queryDef := range cm.Functions {
+	var constraint *Constraint
+	if IsVersionConstraint(version) {
+		c, err := ParseConstraint(version)
+		if err != nil {
+			return nil, err
+		}
+		constraint = &c
+	}
+
+	for _, queryDef := range functions {
 		if lowercase {
 			if !strings.Contains(strings.ToLower(queryDef.GroupName()), strings.ToLower(groupName)) {
 				continue
@@ -268,7 +623,11 @@ func (cm *CatalogManager) Search(fname string, lowercase bool) (fns []FunctionDe
 		if version != "" {
 			var versions []FunctionVersion
 			for _, queryVersion := range queryDef.Versions {
-				if queryVersion.Name == version {
+				if constraint != nil {
+					if constraint.Check(queryVersion.Name, true) {
+						versions = append(versions, queryVersion)
+					}
+				} else if VersionNamesEqual(queryVersion.Name, version) {
 					versions = append(versions, queryVersion)
 				}
 			}
@@ -282,5 +641,9 @@ func (cm *CatalogManager) Search(fname string, lowercase bool) (fns []FunctionDe
 		fns = append(fns, queryDef)
 	}
 
+	sort.Slice(fns, func(i, j int) bool {
+		return fns[i].GroupName() < fns[j].GroupName()
+	})
+
 	return fns, nil
 }
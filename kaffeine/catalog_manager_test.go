@@ -0,0 +1,110 @@
+package kaffeine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func makeTestCatalog(name string, description string, versions ...string) FunctionCatalog {
+	cat := MakeFunctionCatalog(name)
+	fd := FunctionDefinition{Group: "konveyor.io", Description: description}
+	fd.Names.Kind = "RouteFunction"
+	for _, v := range versions {
+		fd.Versions = append(fd.Versions, FunctionVersion{Name: v, Usage: name})
+	}
+	cat.Spec.KrmFunctions = append(cat.Spec.KrmFunctions, fd)
+	return cat
+}
+
+func makeTestMirrors(t *testing.T) *FunctionManager {
+	t.Helper()
+	catman := MakeCatalogManager(t.TempDir())
+	if err := catman.AddCatalogFromStruct("file:///upstream.yaml", makeTestCatalog("upstream", "Upstream", "v1.0.0", "v2.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := catman.AddCatalogFromStruct("file:///mirror.yaml", makeTestCatalog("mirror", "Mirror", "v1.0.0", "v1.5.0")); err != nil {
+		t.Fatal(err)
+	}
+
+	return &FunctionManager{CatMan: &catman, Cfg: &Config{}, Store: MakeBinaryStore(t.TempDir()), Installed: map[string]FunctionDefinition{}, Unresolved: map[string]string{}}
+}
+
+func TestCatalogPriority(t *testing.T) {
+	fm := makeTestMirrors(t)
+	cm := fm.CatMan
+
+	fd := cm.Functions["konveyor.io/RouteFunction"]
+	versions := []string{}
+	usages := []string{}
+	for _, v := range fd.Versions {
+		versions = append(versions, v.Name)
+		usages = append(usages, v.Usage)
+	}
+	if fd.Description != "Upstream" || !reflect.DeepEqual(versions, []string{"v1.0.0", "v2.0.0", "v1.5.0"}) || !reflect.DeepEqual(usages, []string{"upstream", "upstream", "mirror"}) {
+		t.Errorf("got %s with versions %v from %v, want the upstream function merged with the mirror", fd.Description, versions, usages)
+	}
+
+	if uri, _ := cm.FindCatalog("konveyor.io/RouteFunction"); uri != "file:///upstream.yaml" {
+		t.Errorf("got catalog %s, want the upstream one", uri)
+	}
+	if uri, _ := cm.FindVersionCatalog("konveyor.io/RouteFunction", "v1.5.0"); uri != "file:///mirror.yaml" {
+		t.Errorf("got catalog %s for v1.5.0, want the mirror", uri)
+	}
+
+	cm.Reorder([]string{"file:///mirror.yaml"})
+	if fd := cm.Functions["konveyor.io/RouteFunction"]; fd.Description != "Mirror" || fd.Versions[0].Usage != "mirror" {
+		t.Errorf("got %s, want the mirror to take precedence", fd.Description)
+	}
+	if !reflect.DeepEqual(cm.Priority, []string{"file:///mirror.yaml", "file:///upstream.yaml"}) {
+		t.Errorf("got priority %v", cm.Priority)
+	}
+
+	if _, err := cm.RemoveCatalog("file:///mirror.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if fd := cm.Functions["konveyor.io/RouteFunction"]; fd.Description != "Upstream" || len(fd.Versions) != 2 {
+		t.Errorf("got %s with %d versions, want only the upstream function", fd.Description, len(fd.Versions))
+	}
+}
+
+func TestCatalogAlias(t *testing.T) {
+	var tests = []struct {
+		fname, version, usage, config string
+	}{
+		{"konveyor.io/RouteFunction", "v2.0.0", "upstream", "konveyor.io/RouteFunction"},
+		{"konveyor.io/RouteFunction@v1.5.0", "v1.5.0", "mirror", "konveyor.io/RouteFunction@v1.5.0"},
+		{"mirror::konveyor.io/RouteFunction", "v1.5.0", "mirror", "mirror::konveyor.io/RouteFunction"},
+		{"file:///mirror.yaml::konveyor.io/RouteFunction@v1.0.0", "v1.0.0", "mirror", "file:///mirror.yaml::konveyor.io/RouteFunction@v1.0.0"},
+		{"upstream::konveyor.io/RouteFunction@^1.0", "v1.0.0", "upstream", "upstream::konveyor.io/RouteFunction@^1.0"},
+	}
+
+	for _, test := range tests {
+		fm := makeTestMirrors(t)
+		fd, err := fm.GetExternalFunctionDefinition(test.fname)
+		if err != nil {
+			t.Errorf("%s: %v", test.fname, err)
+			continue
+		}
+		if fd.Versions[0].Name != test.version || fd.Versions[0].Usage != test.usage {
+			t.Errorf("%s: got %s from %s, want %s from %s", test.fname, fd.Versions[0].Name, fd.Versions[0].Usage, test.version, test.usage)
+		}
+
+		fm.Installed[fd.GroupName()] = fd
+		fm.UpdateConfig()
+		if got := fm.Cfg.Dependencies.KrmFunctions; len(got) != 1 || got[0] != test.config {
+			t.Errorf("%s: got config %v, want %s", test.fname, got, test.config)
+		}
+	}
+
+	fm := makeTestMirrors(t)
+	for _, fname := range []string{"unknown::konveyor.io/RouteFunction", "mirror::konveyor.io/Other"} {
+		if _, err := fm.GetExternalFunctionDefinition(fname); err == nil {
+			t.Errorf("%s: got no error", fname)
+		}
+	}
+
+	info, err := fm.GetFunctionInfo("mirror::konveyor.io/RouteFunction")
+	if err != nil || info.Catalog != "file:///mirror.yaml" || info.Definition.Description != "Mirror" {
+		t.Errorf("got %+v (%v), want the mirror's definition", info, err)
+	}
+}
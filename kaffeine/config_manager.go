@@ -2,52 +2,296 @@ package kaffeine
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"sigs.k8s.io/yaml"
 )
 
-// Config struct. Unmarshalled from ".kaffeine/config.yaml"
+// Config struct. Unmarshalled from ".kaffeine/config.yaml", merged with the
+// user-global config (see GlobalConfigPath)
 type Config struct {
 	// The filepath, usually ".kaffeine/config.yaml"
 	FilePath string `json:"-"`
 
-	// The list of catalog URIs that kaffeine should manage
-	Catalogs []string `json:"catalogs"`
+	// The catalogs that kaffeine should manage. Configs that list bare URIs are
+	// migrated when saved, see CatalogConfig.UnmarshalJSON
+	Catalogs []CatalogConfig `json:"catalogs"`
 
 	// The list of dependencies that kaffeine should manage. In the future, this
-	// could be extended to pipelines and indirect dependencies.
+	// could be extended to indirect dependencies.
 	Dependencies struct {
 		// The list of KRM Functions to manage. They should be in the format
 		// "Group/Name" and can be followed by "@Version" to peg it to a specific
-		// version
+		// version, or by a range such as "@^1.2" or "@>=2.0.0 <3.0.0" to only
+		// update within that range (see Constraint)
 		KrmFunctions []string `json:"krmFunctions"`
 	} `json:"dependencies"`
+
+	// Named pipelines of installed functions, run with RunPipeline
+	Pipelines map[string]Pipeline `json:"pipelines,omitempty"`
+
+	// Credentials to fetch catalogs and binaries with, keyed by host or by the
+	// auth of a catalog, see CatalogConfig.Auth
+	Credentials map[string]Credential `json:"credentials,omitempty"`
+
+	// Proxy, CA bundle, timeout and retries of the HTTP client, see
+	// MakeHTTPClient
+	HTTP *HTTPConfig `json:"http,omitempty"`
+
+	// Where downloaded binaries are stored. See GetStoreDirectory
+	StoreDirectory string `json:"storeDirectory,omitempty"`
+
+	// The file each value was read from, keyed like ConfigValue.Key (plus the
+	// value for lists). Values without an origin are new.
+	origins map[string]string
+}
+
+// Credentials for a single host. A token is sent as a bearer token, otherwise
+// username and password are used for basic auth.
+type Credential struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
 }
 
-// Creates config struct
+// A single value of the config and the file it came from
+type ConfigValue struct {
+	Key    string
+	Value  string
+	Origin string
+}
+
+// Returns the path of the user-global config, $KAFFEINE_GLOBAL_CONFIG or
+// "~/.kaffeine/config.yaml"
+func GlobalConfigPath() string {
+	if path := os.Getenv("KAFFEINE_GLOBAL_CONFIG"); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kaffeine", "config.yaml")
+}
+
+// Creates config struct from the config in directory, merged with the
+// user-global config. Values of the local config take precedence, and only
+// they are written back by Save.
 func MakeConfig(directory string) (c Config) {
+	c = readConfig(filepath.Join(directory, "config.yaml"))
+
+	if globalPath := GlobalConfigPath(); globalPath != "" && !sameFile(globalPath, c.FilePath) {
+		c.merge(readConfig(globalPath))
+		nameCatalogs(c.Catalogs)
+	}
+
+	return
+}
+
+// Creates config struct from the user-global config alone
+func MakeGlobalConfig() Config {
+	return readConfig(GlobalConfigPath())
+}
+
+func readConfig(filePath string) (c Config) {
 	var data []byte
-	filePath := filepath.Join(directory, "config.yaml")
 
 	if _, err := os.Stat(filePath); !errors.Is(err, os.ErrNotExist) {
 		data, _ = os.ReadFile(filePath)
 	}
 
 	yaml.Unmarshal(data, &c)
+	nameCatalogs(c.Catalogs)
 
 	c.FilePath = filePath
+	c.origins = map[string]string{}
+	for _, key := range c.keys() {
+		c.origins[key] = filePath
+	}
 	return
 }
 
-// Saves config struct
+// Adds the values of other that are not set in c
+func (c *Config) merge(other Config) {
+	for _, cc := range other.Catalogs {
+		if _, ok := c.origins["catalogs/"+cc.Uri]; !ok {
+			c.Catalogs = append(c.Catalogs, cc)
+			c.origins["catalogs/"+cc.Uri] = other.origins["catalogs/"+cc.Uri]
+		}
+	}
+
+	for name, pipeline := range other.Pipelines {
+		if _, ok := c.Pipelines[name]; !ok {
+			if c.Pipelines == nil {
+				c.Pipelines = map[string]Pipeline{}
+			}
+			c.Pipelines[name] = pipeline
+			c.origins["pipelines."+name] = other.origins["pipelines."+name]
+		}
+	}
+
+	for host, cred := range other.Credentials {
+		if _, ok := c.Credentials[host]; !ok {
+			if c.Credentials == nil {
+				c.Credentials = map[string]Credential{}
+			}
+			c.Credentials[host] = cred
+			c.origins["credentials."+host] = other.origins["credentials."+host]
+		}
+	}
+
+	if c.StoreDirectory == "" && other.StoreDirectory != "" {
+		c.StoreDirectory = other.StoreDirectory
+		c.origins["storeDirectory"] = other.origins["storeDirectory"]
+	}
+
+	if c.HTTP == nil && other.HTTP != nil {
+		c.HTTP = other.HTTP
+		c.origins["http"] = other.origins["http"]
+	}
+}
+
+// Returns the keys of the origins of every value set in the config
+func (c *Config) keys() (keys []string) {
+	for _, cc := range c.Catalogs {
+		keys = append(keys, "catalogs/"+cc.Uri)
+	}
+	for _, fname := range c.Dependencies.KrmFunctions {
+		keys = append(keys, "dependencies.krmFunctions/"+fname)
+	}
+	for name := range c.Pipelines {
+		keys = append(keys, "pipelines."+name)
+	}
+	for host := range c.Credentials {
+		keys = append(keys, "credentials."+host)
+	}
+	if c.StoreDirectory != "" {
+		keys = append(keys, "storeDirectory")
+	}
+	if c.HTTP != nil {
+		keys = append(keys, "http")
+	}
+	return
+}
+
+// Returns the file the value was read from, or "" if it was not read from any
+func (c *Config) Origin(key string) string {
+	return c.origins[key]
+}
+
+// Reports whether the value belongs in the file of the config itself, rather
+// than being merged in from another one
+func (c *Config) isOwn(key string) bool {
+	origin, ok := c.origins[key]
+	return !ok || origin == c.FilePath
+}
+
+// Returns every value of the config with the file it came from, in the order
+// they appear in. Credentials are redacted.
+func (c *Config) Values() (values []ConfigValue) {
+	add := func(key string, originKey string, value string) {
+		values = append(values, ConfigValue{Key: key, Value: value, Origin: c.origins[originKey]})
+	}
+
+	for _, cc := range c.Catalogs {
+		add("catalogs", "catalogs/"+cc.Uri, cc.String())
+	}
+	for _, fname := range c.Dependencies.KrmFunctions {
+		add("dependencies.krmFunctions", "dependencies.krmFunctions/"+fname, fname)
+	}
+	for _, name := range c.PipelineNames() {
+		steps := []string{}
+		for _, step := range c.Pipelines[name] {
+			steps = append(steps, step.String())
+		}
+		add("pipelines."+name, "pipelines."+name, strings.Join(steps, " | "))
+	}
+
+	hosts := make([]string, 0, len(c.Credentials))
+	for host := range c.Credentials {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		add("credentials."+host, "credentials."+host, c.Credentials[host].String())
+	}
+
+	if c.StoreDirectory != "" {
+		add("storeDirectory", "storeDirectory", c.StoreDirectory)
+	}
+	if c.HTTP != nil {
+		add("http", "http", c.HTTP.String())
+	}
+
+	return values
+}
+
+// Returns a copy of the config with the secrets of its credentials replaced
+func (c Config) Redacted() Config {
+	if len(c.Credentials) == 0 {
+		return c
+	}
+
+	credentials := map[string]Credential{}
+	for host, cred := range c.Credentials {
+		if cred.Password != "" {
+			cred.Password = "<redacted>"
+		}
+		if cred.Token != "" {
+			cred.Token = "<redacted>"
+		}
+		credentials[host] = cred
+	}
+	c.Credentials = credentials
+	return c
+}
+
+func (cred Credential) String() string {
+	parts := []string{}
+	if cred.Username != "" {
+		parts = append(parts, "username="+cred.Username)
+	}
+	if cred.Password != "" {
+		parts = append(parts, "password=<redacted>")
+	}
+	if cred.Token != "" {
+		parts = append(parts, "token=<redacted>")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Returns where downloaded binaries are stored: $KAFFEINE_STORE, the
+// storeDirectory of the config, or DefaultStoreDirectory(). A leading "~/" is
+// expanded to the home directory.
+func (c *Config) GetStoreDirectory() string {
+	if os.Getenv("KAFFEINE_STORE") != "" || c.StoreDirectory == "" {
+		return DefaultStoreDirectory()
+	}
+
+	if strings.HasPrefix(c.StoreDirectory, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, c.StoreDirectory[2:])
+		}
+	}
+	return c.StoreDirectory
+}
+
+// Saves config struct. Values merged in from the global config are left out.
 func (c *Config) Save() error {
-	data, err := yaml.Marshal(c)
+	data, err := yaml.Marshal(c.own())
 	if err != nil {
 		return err
 	}
 
+	if err := os.MkdirAll(filepath.Dir(c.FilePath), os.ModePerm); err != nil {
+		return err
+	}
+
 	file, err := os.Create(c.FilePath)
 	if err != nil {
 		return err
@@ -57,3 +301,83 @@ func (c *Config) Save() error {
 	file.Write(data)
 	return nil
 }
+
+// Returns the part of the config that belongs in its own file
+func (c *Config) own() Config {
+	o := Config{FilePath: c.FilePath, Catalogs: []CatalogConfig{}}
+	o.Dependencies = c.Dependencies
+
+	for _, cc := range c.Catalogs {
+		if c.isOwn("catalogs/" + cc.Uri) {
+			o.Catalogs = append(o.Catalogs, cc)
+		}
+	}
+	for name, pipeline := range c.Pipelines {
+		if c.isOwn("pipelines." + name) {
+			if o.Pipelines == nil {
+				o.Pipelines = map[string]Pipeline{}
+			}
+			o.Pipelines[name] = pipeline
+		}
+	}
+	for host, cred := range c.Credentials {
+		if c.isOwn("credentials." + host) {
+			if o.Credentials == nil {
+				o.Credentials = map[string]Credential{}
+			}
+			o.Credentials[host] = cred
+		}
+	}
+	if c.isOwn("storeDirectory") {
+		o.StoreDirectory = c.StoreDirectory
+	}
+	if c.isOwn("http") {
+		o.HTTP = c.HTTP
+	}
+
+	return o
+}
+
+// Adds the catalog to the config, failing if its uri or name is already
+// present. Catalogs without a name are named after their uri.
+func (c *Config) AddCatalog(cc CatalogConfig) error {
+	if i := c.catalogIndex(cc.Uri); i >= 0 {
+		return fmt.Errorf("catalog '%s' already present in '%s'", cc.Uri, c.Origin("catalogs/"+cc.Uri))
+	}
+
+	if cc.Name == "" {
+		catalogs := append(append([]CatalogConfig{}, c.Catalogs...), cc)
+		nameCatalogs(catalogs)
+		cc.Name = catalogs[len(catalogs)-1].Name
+	} else if i := c.catalogIndex(cc.Name); i >= 0 {
+		return fmt.Errorf("catalog name '%s' already used by '%s'", cc.Name, c.Catalogs[i].Uri)
+	}
+
+	if err := cc.Validate(); err != nil {
+		return err
+	}
+
+	c.Catalogs = append(c.Catalogs, cc)
+	return nil
+}
+
+// Removes the catalog with the given name or uri from the config
+func (c *Config) RemoveCatalog(nameOrUri string) (cc CatalogConfig, err error) {
+	i := c.catalogIndex(nameOrUri)
+	if i < 0 {
+		return cc, fmt.Errorf("catalog '%s' not present in '%s'", nameOrUri, c.FilePath)
+	}
+
+	cc = c.Catalogs[i]
+	c.Catalogs = append(c.Catalogs[:i], c.Catalogs[i+1:]...)
+	return cc, nil
+}
+
+func sameFile(a string, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(infoA, infoB)
+}
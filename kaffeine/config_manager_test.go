@@ -0,0 +1,144 @@
+package kaffeine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testGlobalConfig = `catalogs:
+- https://example.com/global.yaml
+- https://example.com/shared.yaml
+pipelines:
+  deploy:
+  - function: example.com/Global
+credentials:
+  example.com:
+    username: bob
+    password: hunter2
+storeDirectory: /global/store
+`
+
+const testLocalConfig = `catalogs:
+- https://example.com/local.yaml
+- https://example.com/shared.yaml
+dependencies:
+  krmFunctions:
+  - example.com/Logger
+pipelines:
+  deploy:
+  - function: example.com/Local
+`
+
+func writeTestConfigs(t *testing.T) (dir string, globalPath string) {
+	t.Helper()
+	globalPath = filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(globalPath, []byte(testGlobalConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KAFFEINE_GLOBAL_CONFIG", globalPath)
+
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(testLocalConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, globalPath
+}
+
+func TestMakeConfigMergesGlobal(t *testing.T) {
+	dir, globalPath := writeTestConfigs(t)
+	localPath := filepath.Join(dir, "config.yaml")
+
+	c := MakeConfig(dir)
+	if want := "[https://example.com/local.yaml https://example.com/shared.yaml https://example.com/global.yaml]"; fmt.Sprint(c.CatalogUris()) != want {
+		t.Errorf("got catalogs %v, want %s", c.CatalogUris(), want)
+	}
+	if c.Pipelines["deploy"][0].Function != "example.com/Local" {
+		t.Errorf("local pipeline does not take precedence, got %v", c.Pipelines["deploy"])
+	}
+	if c.Credentials["example.com"].Username != "bob" || c.StoreDirectory != "/global/store" {
+		t.Errorf("global values not merged, got %v and %s", c.Credentials, c.StoreDirectory)
+	}
+
+	var tests = []struct {
+		key, origin string
+	}{
+		{"catalogs/https://example.com/local.yaml", localPath},
+		{"catalogs/https://example.com/shared.yaml", localPath},
+		{"catalogs/https://example.com/global.yaml", globalPath},
+		{"dependencies.krmFunctions/example.com/Logger", localPath},
+		{"pipelines.deploy", localPath},
+		{"credentials.example.com", globalPath},
+		{"storeDirectory", globalPath},
+	}
+	for _, test := range tests {
+		if got := c.Origin(test.key); got != test.origin {
+			t.Errorf("%s: got origin %s, want %s", test.key, got, test.origin)
+		}
+	}
+
+	for _, v := range c.Values() {
+		if strings.Contains(v.Value, "hunter2") {
+			t.Errorf("%s: password not redacted", v.Key)
+		}
+	}
+	if strings.Contains(fmt.Sprint(c.Redacted().Credentials), "hunter2") {
+		t.Errorf("password not redacted")
+	}
+}
+
+func TestSaveConfigLeavesOutGlobal(t *testing.T) {
+	dir, globalPath := writeTestConfigs(t)
+
+	c := MakeConfig(dir)
+	c.Catalogs = append(c.Catalogs, CatalogConfig{Name: "new", Uri: "https://example.com/new.yaml"})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	local := readConfig(filepath.Join(dir, "config.yaml"))
+	if want := "[https://example.com/local.yaml https://example.com/shared.yaml https://example.com/new.yaml]"; fmt.Sprint(local.CatalogUris()) != want {
+		t.Errorf("saved catalogs %v, want %s", local.CatalogUris(), want)
+	}
+	if len(local.Credentials) != 0 || local.StoreDirectory != "" {
+		t.Errorf("global values saved to local config: %v, %s", local.Credentials, local.StoreDirectory)
+	}
+
+	global := MakeGlobalConfig()
+	if err := global.AddCatalog(CatalogConfig{Uri: "https://example.com/global.yaml"}); err == nil {
+		t.Errorf("expected error adding catalog twice")
+	}
+	if _, err := global.RemoveCatalog("global"); err != nil {
+		t.Fatal(err)
+	}
+	if err := global.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved := readConfig(globalPath)
+	if fmt.Sprint(saved.CatalogUris()) != "[https://example.com/shared.yaml]" || saved.Credentials["example.com"].Password != "hunter2" {
+		t.Errorf("global config not saved, got %v and %v", saved.Catalogs, saved.Credentials)
+	}
+}
+
+func TestGetDirectorySkipsGlobal(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("KAFFEINE_GLOBAL_CONFIG", filepath.Join(home, ".kaffeine", "config.yaml"))
+	if err := os.MkdirAll(filepath.Join(home, ".kaffeine"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(home); err != nil {
+		t.Fatal(err)
+	}
+
+	if dir, err := GetDirectory(); err != ErrNoDirectory {
+		t.Errorf("got directory %s (%v), want ErrNoDirectory", dir, err)
+	}
+}
@@ -0,0 +1,268 @@
+package kaffeine
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// A range of semantic versions, e.g. "^1.2", "~1.0.3" or ">=2.0.0 <3.0.0".
+//
+// Comparators separated by whitespace or commas must all be satisfied, while
+// "||" separates alternative ranges. The supported comparators are:
+//   - "=", "!=", "<", "<=", ">", ">=" compare against the given version
+//   - "^1.2.3" allows changes that do not modify the left-most non-zero number
+//     (>=1.2.3 <2.0.0, while ^0.2.3 means >=0.2.3 <0.3.0)
+//   - "~1.2.3" allows patch-level changes (>=1.2.3 <1.3.0)
+//   - "1.2", "1.2.x" and "1.2.*" allow any version with the given prefix, and
+//     "*" allows any version at all
+//
+// Pre-releases only satisfy a range if one of its comparators refers to a
+// pre-release of the same major, minor and patch version, so ">=1.0.0-rc.1"
+// matches "1.0.0-rc.2" but not "1.1.0-rc.1". Upper bounds never admit the
+// pre-releases of the bound itself, so "<2.0.0" and "^1.2" do not match
+// "2.0.0-rc.1" even when pre-releases are included.
+type Constraint struct {
+	raw  string
+	sets [][]comparator
+}
+
+type comparator struct {
+	op string
+	v  Version
+}
+
+// Reports whether the version string is a range rather than a single exact
+// version. Used to tell "group/name@v1.0.0" apart from "group/name@^1.0".
+func IsVersionConstraint(s string) bool {
+	if strings.ContainsAny(s, "^~<>=!*|, \t") {
+		return true
+	}
+	for _, part := range strings.Split(strings.TrimPrefix(s, "v"), ".") {
+		if isWildcard(part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parses the given range. See Constraint for the syntax.
+func ParseConstraint(s string) (c Constraint, err error) {
+	c.raw = s
+
+	for _, alt := range strings.Split(s, "||") {
+		tokens := strings.FieldsFunc(alt, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ','
+		})
+		if len(tokens) == 0 {
+			return c, fmt.Errorf("invalid version constraint '%s': empty range", s)
+		}
+
+		set := []comparator{}
+		for i := 0; i < len(tokens); i++ {
+			token := tokens[i]
+			// Allow whitespace between operator and version (">= 1.0.0")
+			if strings.Trim(token, "<>=!^~") == "" && i+1 < len(tokens) {
+				i++
+				token += tokens[i]
+			}
+
+			cmps, err := parseComparator(token)
+			if err != nil {
+				return c, fmt.Errorf("invalid version constraint '%s': %v", s, err)
+			}
+			set = append(set, cmps...)
+		}
+		c.sets = append(c.sets, set)
+	}
+
+	return c, nil
+}
+
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// Reports whether the version name satisfies the constraint. Names that are
+// not semantic versions never do. If includePrerelease is set, pre-releases
+// are treated like any other version.
+func (c Constraint) Check(name string, includePrerelease bool) bool {
+	v, err := ParseVersion(name)
+	if err != nil {
+		return false
+	}
+
+	for _, set := range c.sets {
+		ok := true
+		for _, cmp := range set {
+			if !cmp.check(v) {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		if !v.IsPrerelease() || includePrerelease {
+			return true
+		}
+		for _, cmp := range set {
+			if cmp.v.IsPrerelease() && cmp.v.Major == v.Major && cmp.v.Minor == v.Minor && cmp.v.Patch == v.Patch {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (cmp comparator) check(v Version) bool {
+	c := v.Compare(cmp.v)
+	switch cmp.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
+
+// Turns a single comparator token into primitive comparators, expanding
+// carets, tildes and partial versions into ranges
+func parseComparator(token string) ([]comparator, error) {
+	op := ""
+	for _, o := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(token, o) {
+			op = o
+			token = token[len(o):]
+			break
+		}
+	}
+
+	v, n, err := parsePartialVersion(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wildcards match everything
+	if n == 0 {
+		switch op {
+		case "", "=", "^", "~", ">=", "<=":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("'%s' cannot be used with a wildcard", op)
+	}
+
+	between := func(lower, upper Version) []comparator {
+		return []comparator{{">=", lower}, {"<", lowestPrerelease(upper)}}
+	}
+
+	switch op {
+	case "", "=":
+		if n == 3 {
+			return []comparator{{"=", v}}, nil
+		}
+		return between(v, bumpVersion(v, n)), nil
+	case "!=":
+		if n != 3 {
+			return nil, fmt.Errorf("'!=' requires a full version, got '%s'", token)
+		}
+		return []comparator{{"!=", v}}, nil
+	case ">":
+		if n != 3 {
+			return []comparator{{">=", bumpVersion(v, n)}}, nil
+		}
+		return []comparator{{">", v}}, nil
+	case "<=":
+		if n != 3 {
+			return []comparator{{"<", lowestPrerelease(bumpVersion(v, n))}}, nil
+		}
+		return []comparator{{"<=", v}}, nil
+	case "<":
+		return []comparator{{"<", lowestPrerelease(v)}}, nil
+	case ">=":
+		return []comparator{{">=", v}}, nil
+	case "~":
+		if n == 1 {
+			return between(v, bumpVersion(v, 1)), nil
+		}
+		return between(v, bumpVersion(v, 2)), nil
+	case "^":
+		switch {
+		case v.Major > 0 || n == 1:
+			return between(v, bumpVersion(v, 1)), nil
+		case v.Minor > 0 || n == 2:
+			return between(v, bumpVersion(v, 2)), nil
+		}
+		return between(v, bumpVersion(v, 3)), nil
+	}
+
+	return nil, fmt.Errorf("unknown operator '%s'", op)
+}
+
+// Parses a version that may end in wildcards or leave out components. Returns
+// the number of components that were given (0 for "*").
+func parsePartialVersion(s string) (v Version, n int, err error) {
+	core := strings.TrimPrefix(s, "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+
+	parts := strings.Split(core, ".")
+	for n < len(parts) && !isWildcard(parts[n]) {
+		n++
+	}
+
+	if n < len(parts) {
+		for _, part := range parts[n:] {
+			if !isWildcard(part) {
+				return v, 0, fmt.Errorf("invalid wildcard version '%s'", s)
+			}
+		}
+		if len(core) != len(strings.TrimPrefix(s, "v")) {
+			return v, 0, fmt.Errorf("wildcard version '%s' cannot have a pre-release or build metadata", s)
+		}
+		if n == 0 {
+			return v, 0, nil
+		}
+		s = strings.Join(parts[:n], ".")
+	}
+
+	v, err = ParseVersion(s)
+	return v, n, err
+}
+
+// Returns the lowest version above every version sharing the first n
+// components of v
+func bumpVersion(v Version, n int) Version {
+	switch n {
+	case 1:
+		return Version{Major: v.Major + 1}
+	case 2:
+		return Version{Major: v.Major, Minor: v.Minor + 1}
+	}
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+}
+
+// Returns the lowest pre-release of the version, so that an upper bound like
+// "<2.0.0" also excludes the pre-releases of 2.0.0. Pre-releases are returned
+// unchanged.
+func lowestPrerelease(v Version) Version {
+	if !v.IsPrerelease() {
+		v.Prerelease = []string{"0"}
+	}
+	return v
+}
+
+func isWildcard(s string) bool {
+	return s == "x" || s == "X" || s == "*"
+}
@@ -0,0 +1,95 @@
+package kaffeine
+
+import (
+	"testing"
+)
+
+func TestIsVersionConstraint(t *testing.T) {
+	var tests = []struct {
+		in         string
+		constraint bool
+	}{
+		{"v1.0.0", false},
+		{"v3", false},
+		{"latest", false},
+		{"^1.2", true},
+		{"~1.0.3", true},
+		{">=2.0.0 <3.0.0", true},
+		{"1.x", true},
+		{"*", true},
+		{"1.0.0 || 2.0.0", true},
+	}
+
+	for _, test := range tests {
+		if got := IsVersionConstraint(test.in); got != test.constraint {
+			t.Errorf("%s: got %v, want %v", test.in, got, test.constraint)
+		}
+	}
+}
+
+func TestConstraintCheck(t *testing.T) {
+	var tests = []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{"^1.2", []string{"v1.2.0", "v1.9.9", "1.2.5"}, []string{"v1.1.9", "v2.0.0", "v2.0.0-rc.1", "v1.3.0-rc.1", "latest"}},
+		{"^1.2.3", []string{"v1.2.3", "v1.5.0"}, []string{"v1.2.2", "v2.0.0"}},
+		{"^0.2.3", []string{"v0.2.3", "v0.2.9"}, []string{"v0.3.0", "v0.2.2"}},
+		{"^0.0.3", []string{"v0.0.3"}, []string{"v0.0.4"}},
+		{"~1.0.3", []string{"v1.0.3", "v1.0.10"}, []string{"v1.0.2", "v1.1.0"}},
+		{"~1", []string{"v1.0.0", "v1.9.0"}, []string{"v2.0.0"}},
+		{">=2.0.0 <3.0.0", []string{"v2.0.0", "v2.10.1"}, []string{"v1.9.9", "v3.0.0", "v3.0.0-rc.1"}},
+		{">= 2.0.0, < 3.0.0", []string{"v2.5.0"}, []string{"v3.0.0"}},
+		{">1.2", []string{"v1.3.0"}, []string{"v1.2.9"}},
+		{"<=1.2", []string{"v1.2.9", "v1.0.0"}, []string{"v1.3.0"}},
+		{"1.2.x", []string{"v1.2.0", "v1.2.7"}, []string{"v1.3.0"}},
+		{"*", []string{"v0.0.1", "v10.0.0", "v3"}, []string{"v1.0.0-rc.1", "latest"}},
+		{"^1.0.0 || ^3.0.0", []string{"v1.1.0", "v3.2.0"}, []string{"v2.0.0"}},
+		{">=1.0.0-rc.1 <2.0.0", []string{"v1.0.0-rc.2", "v1.0.0"}, []string{"v1.1.0-rc.1"}},
+		{"!=1.0.1 ^1", []string{"v1.0.0", "v1.0.2"}, []string{"v1.0.1"}},
+	}
+
+	for _, test := range tests {
+		c, err := ParseConstraint(test.constraint)
+		if err != nil {
+			t.Errorf("%s: %v", test.constraint, err)
+			continue
+		}
+		for _, v := range test.match {
+			if !c.Check(v, false) {
+				t.Errorf("%s: expected %s to match", test.constraint, v)
+			}
+		}
+		for _, v := range test.noMatch {
+			if c.Check(v, false) {
+				t.Errorf("%s: expected %s not to match", test.constraint, v)
+			}
+		}
+	}
+
+	c, _ := ParseConstraint("^1.2")
+	if !c.Check("v1.3.0-rc.1", true) {
+		t.Errorf("^1.2: expected pre-release to match when included")
+	}
+
+	// Upper bounds exclude the pre-releases of the bound, unless it is one
+	for _, constraint := range []string{"^1.2", "<2.0.0", "<=1", "1.x"} {
+		c, _ := ParseConstraint(constraint)
+		if c.Check("v2.0.0-rc.1", true) {
+			t.Errorf("%s: expected pre-release of the upper bound not to match", constraint)
+		}
+	}
+	c, _ = ParseConstraint("<2.0.0-rc.2")
+	if !c.Check("v2.0.0-rc.1", true) {
+		t.Errorf("<2.0.0-rc.2: expected lower pre-release to match when included")
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, in := range []string{"", ">=", "^foo", "1.x.2", ">*", "!=1.2", "1.0.0 ||"} {
+		if _, err := ParseConstraint(in); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package kaffeine
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Runs functions with a container runtime by shelling out to the CLI of a
+// container engine such as docker or podman
+type ContainerRunner struct {
+	// Name or path of the engine binary. If empty, $KAFFEINE_CONTAINER_ENGINE is
+	// used, or else the first of ContainerEngines found in $PATH
+	Engine string
+
+	// Directory mounted into functions that require a storage mount. Defaults
+	// to the working directory.
+	MountDirectory string
+}
+
+// The container engines looked for when none is configured, in order
+var ContainerEngines = []string{"docker", "podman"}
+
+// Returns the path of the container engine binary to shell out to
+func (r ContainerRunner) EnginePath() (string, error) {
+	engine := r.Engine
+	if engine == "" {
+		engine = os.Getenv("KAFFEINE_CONTAINER_ENGINE")
+	}
+	if engine != "" {
+		return exec.LookPath(engine)
+	}
+
+	for _, engine := range ContainerEngines {
+		if path, err := exec.LookPath(engine); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("no container engine found (install one of %s, or set KAFFEINE_CONTAINER_ENGINE)", strings.Join(ContainerEngines, ", "))
+}
+
+// Returns the image to run. If the runtime declares a sha256, the image is
+// pinned to that digest.
+func ImageReference(c FunctionRuntimeContainer) (string, error) {
+	if c.Image == "" {
+		return "", fmt.Errorf("container runtime has no image")
+	}
+	if c.Sha256 == "" {
+		return c.Image, nil
+	}
+
+	sha := strings.TrimPrefix(strings.ToLower(c.Sha256), "sha256:")
+	if !IsValidSha256(sha) {
+		return "", fmt.Errorf("image '%s' declares invalid sha256 '%s'", c.Image, c.Sha256)
+	}
+
+	digest := "sha256:" + sha
+	if i := strings.LastIndex(c.Image, "@"); i >= 0 {
+		if strings.ToLower(c.Image[i+1:]) != digest {
+			return "", fmt.Errorf("image '%s' does not match declared sha256 '%s'", c.Image, c.Sha256)
+		}
+		return c.Image, nil
+	}
+
+	return c.Image + "@" + digest, nil
+}
+
+// Returns the arguments to the container engine to run the function. Networking
+// is disabled unless the function requires it, and the mount directory is only
+// mounted for functions that require storage.
+func (r ContainerRunner) Args(c FunctionRuntimeContainer) ([]string, error) {
+	image, err := ImageReference(c)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"run", "--rm", "-i"}
+	if !c.RequireNetwork {
+		args = append(args, "--network", "none")
+	}
+	if c.RequireStorageMount {
+		dir := r.MountDirectory
+		if dir == "" {
+			if dir, err = os.Getwd(); err != nil {
+				return nil, err
+			}
+		}
+		if dir, err = filepath.Abs(dir); err != nil {
+			return nil, err
+		}
+		args = append(args, "--mount", "type=bind,source="+dir+",target="+dir, "--workdir", dir)
+	}
+
+	return append(args, image), nil
+}
+
+// Runs the container function with input piped to its stdin. Errors are
+// reported like RunExecFunction does.
+func (r ContainerRunner) Run(fname string, c FunctionRuntimeContainer, input []byte) (output []byte, err error) {
+	engine, err := r.EnginePath()
+	if err != nil {
+		return nil, fmt.Errorf("could not run function '%s': %w", fname, err)
+	}
+
+	args, err := r.Args(c)
+	if err != nil {
+		return nil, fmt.Errorf("could not run function '%s': %w", fname, err)
+	}
+
+	return runFunctionCommand(fname, exec.Command(engine, args...), input)
+}
@@ -0,0 +1,112 @@
+package kaffeine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testImageSha256 = "71b94c4b99aeeb9432c8110b8cc25609ecc3fa1149bca917b6a10bdacb7aedc1"
+
+func TestContainerRunnerArgs(t *testing.T) {
+	var tests = []struct {
+		name      string
+		container FunctionRuntimeContainer
+		want      string
+		wantErr   bool
+	}{
+		{"no network", FunctionRuntimeContainer{Image: "example.com/fn:v1"}, "run --rm -i --network none example.com/fn:v1", false},
+		{"network", FunctionRuntimeContainer{Image: "example.com/fn:v1", RequireNetwork: true}, "run --rm -i example.com/fn:v1", false},
+		{"storage mount", FunctionRuntimeContainer{Image: "example.com/fn:v1", RequireNetwork: true, RequireStorageMount: true}, "run --rm -i --mount type=bind,source=/work,target=/work --workdir /work example.com/fn:v1", false},
+		{"pinned", FunctionRuntimeContainer{Image: "example.com/fn:v1", Sha256: testImageSha256}, "run --rm -i --network none example.com/fn:v1@sha256:" + testImageSha256, false},
+		{"pinned with prefix", FunctionRuntimeContainer{Image: "example.com/fn:v1", Sha256: "sha256:" + testImageSha256}, "run --rm -i --network none example.com/fn:v1@sha256:" + testImageSha256, false},
+		{"already pinned", FunctionRuntimeContainer{Image: "example.com/fn@sha256:" + testImageSha256, Sha256: testImageSha256}, "run --rm -i --network none example.com/fn@sha256:" + testImageSha256, false},
+		{"pinned to other digest", FunctionRuntimeContainer{Image: "example.com/fn@sha256:" + strings.Repeat("0", 64), Sha256: testImageSha256}, "", true},
+		{"invalid sha256", FunctionRuntimeContainer{Image: "example.com/fn:v1", Sha256: "deadbeef"}, "", true},
+		{"no image", FunctionRuntimeContainer{}, "", true},
+	}
+
+	r := ContainerRunner{MountDirectory: "/work"}
+	for _, test := range tests {
+		args, err := r.Args(test.container)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name, err, test.wantErr)
+			continue
+		}
+		if got := strings.Join(args, " "); got != test.want {
+			t.Errorf("%s: got args %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+// Installs a fake container engine named engine in front of $PATH. It records
+// its arguments to the returned file and echoes its input.
+func writeFakeEngine(t *testing.T, engine string) string {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat\n"
+	if err := os.WriteFile(filepath.Join(dir, engine), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("KAFFEINE_CONTAINER_ENGINE", "")
+	return argsFile
+}
+
+func TestRunContainerFunction(t *testing.T) {
+	argsFile := writeFakeEngine(t, "docker")
+
+	fm := FunctionManager{Installed: map[string]FunctionDefinition{}}
+	fd := FunctionDefinition{Group: "example.com", Versions: []FunctionVersion{{Name: "v1.0.0"}}}
+	fd.Names.Kind = "Logger"
+	fd.Versions[0].Runtime.Container = FunctionRuntimeContainer{Image: "example.com/logger:v1.0.0", Sha256: testImageSha256}
+	fd.Metadata = &v1.ObjectMeta{}
+	fm.Installed[fd.GroupName()] = fd
+
+	output, err := fm.RunFunction("example.com/Logger", []byte(testResourceList), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(output), "name: wordpress") {
+		t.Errorf("input not passed to container, got %q", output)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "run --rm -i --network none example.com/logger:v1.0.0@sha256:" + testImageSha256 + "\n"; string(args) != want {
+		t.Errorf("engine called with %q, want %q", args, want)
+	}
+
+	// Installed binaries take precedence over the container
+	fd.Metadata.Annotations = map[string]string{LocalBinaryLocation: "file://" + writeScript(t, "sed 's+wordpress+exec+g'")}
+	fm.Installed[fd.GroupName()] = fd
+	output, err = fm.RunFunction("example.com/Logger", []byte(testResourceList), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(output), "name: exec") {
+		t.Errorf("binary not preferred over container, got %q", output)
+	}
+}
+
+func TestContainerEnginePath(t *testing.T) {
+	argsFile := writeFakeEngine(t, "podman")
+	t.Setenv("PATH", filepath.Dir(argsFile))
+
+	if path, err := (ContainerRunner{}).EnginePath(); err != nil || filepath.Base(path) != "podman" {
+		t.Errorf("got engine %q (%v), want podman", path, err)
+	}
+	if _, err := (ContainerRunner{Engine: "kaffeine-missing-engine"}).EnginePath(); err == nil {
+		t.Errorf("expected error for engine that is not installed")
+	}
+	t.Setenv("PATH", "")
+	if _, err := (ContainerRunner{}).EnginePath(); err == nil {
+		t.Errorf("expected error with no engine installed")
+	}
+}
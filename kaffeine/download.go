@@ -0,0 +1,66 @@
+package kaffeine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Error returned when downloaded content does not match its declared digest
+type DigestMismatchError struct {
+	Uri      string
+	Expected string
+	Actual   string
+}
+
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("sha256 mismatch for '%s': expected %s, got %s", e.Uri, e.Expected, e.Actual)
+}
+
+// Reports whether s looks like a sha256 hex digest. Catalogs sometimes
+// publish placeholders such as "deadbeef".
+func IsValidSha256(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// Downloads the uri into the file at path, hashing the content while it is
+// being written. Returns the sha256 of the content. If expectedSha256 is not
+// empty and does not match, the file is removed and a *DigestMismatchError is
+// returned.
+func downloadFile(client *HTTPClient, uri string, path string, expectedSha256 string) (digest string, err error) {
+	body, err := client.Open(uri, "")
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return
+	}
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, h), body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	digest = hex.EncodeToString(h.Sum(nil))
+	if expectedSha256 != "" && !strings.EqualFold(digest, expectedSha256) {
+		os.Remove(path)
+		return "", &DigestMismatchError{Uri: uri, Expected: expectedSha256, Actual: digest}
+	}
+
+	return digest, nil
+}
@@ -0,0 +1,60 @@
+package kaffeine
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	content := []byte("#!/bin/sh\ncat\n")
+	if err := os.WriteFile(src, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := fmt.Sprintf("%x", sha256.Sum256(content))
+
+	var tests = []struct {
+		name, expected string
+		mismatch       bool
+	}{
+		{"matching", sum, false},
+		{"no digest", "", false},
+		{"mismatching", "deadbeef", true},
+	}
+
+	for _, test := range tests {
+		dst := filepath.Join(dir, test.name)
+		digest, err := downloadFile(nil, "file://"+src, dst, test.expected)
+
+		var mismatch *DigestMismatchError
+		if errors.As(err, &mismatch) != test.mismatch {
+			t.Errorf("%s: got error %v, want mismatch %v", test.name, err, test.mismatch)
+			continue
+		}
+
+		_, statErr := os.Stat(dst)
+		if test.mismatch {
+			if !errors.Is(statErr, os.ErrNotExist) {
+				t.Errorf("%s: file not removed after mismatch", test.name)
+			}
+			continue
+		}
+		if err != nil || statErr != nil || digest != sum {
+			t.Errorf("%s: got digest %s (errors %v, %v), want %s", test.name, digest, err, statErr, sum)
+		}
+	}
+}
+
+func TestIsValidSha256(t *testing.T) {
+	if IsValidSha256("deadbeef") {
+		t.Errorf("placeholder digest reported as valid")
+	}
+	if !IsValidSha256(fmt.Sprintf("%x", sha256.Sum256(nil))) {
+		t.Errorf("valid digest reported as invalid")
+	}
+}
@@ -1,18 +1,23 @@
 package kaffeine
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 )
 
+// Returned when saving a FunctionManager opened with NewReadOnlyFunctionManager
+var ErrReadOnly = errors.New("function manager was opened read-only")
+
 type FunctionManager struct {
 	Directory string
 
@@ -20,11 +25,40 @@ type FunctionManager struct {
 	Cfg    *Config
 
 	Installed map[string]FunctionDefinition
+
+	// Dependencies from the config that could not be resolved, keyed by their
+	// GroupName. They are kept in the config and lockfile as they were.
+	Unresolved map[string]string
+
+	// Whether Save is refused. See NewReadOnlyFunctionManager
+	ReadOnly bool
+
+	// Where the binaries of installed functions are downloaded to
+	Store BinaryStore
+
+	// Runs functions that only have a container runtime
+	Containers ContainerRunner
+
+	// Whether pre-release versions may be picked when resolving the latest
+	// version of a function
+	AllowPrerelease bool
+
+	// Whether to skip checking downloaded binaries against the sha256 declared
+	// in their catalog
+	SkipDigestVerification bool
+
+	// The platform ("os/arch") to download binaries for. If empty, functions
+	// keep the platform they were installed for, or use the host platform
+	Platform string
+
+	// Whether the installed functions must match the lockfile exactly. See
+	// InstallFrozen
+	Frozen bool
 }
 
 // Traverses the file tree upward, until it finds either a folder named
 // ".kaffeine" or tries to go past "/". If no directory is found, it returns
-// the result of:
+// ErrNoDirectory along with the result of:
 // 	wd, _ := os.Getwd()
 // 	filepath.Join(wd, "/.kaffeine/")`
 func GetDirectory() (dir string, err error) {
@@ -35,10 +69,17 @@ func GetDirectory() (dir string, err error) {
 
 	checkDir := wd
 
+	// The directory of the global config is not a project, even though it is
+	// usually ~/.kaffeine
+	globalDir := ""
+	if globalPath := GlobalConfigPath(); globalPath != "" {
+		globalDir = filepath.Dir(globalPath)
+	}
+
 	for ok := true; ok; ok = (checkDir != "/") {
 		info, err := os.Stat(filepath.Join(checkDir, "/.kaffeine/"))
 
-		if !os.IsNotExist(err) && info.IsDir() {
+		if !os.IsNotExist(err) && info.IsDir() && (globalDir == "" || !sameFile(filepath.Join(checkDir, "/.kaffeine/"), globalDir)) {
 			dir = filepath.Join(checkDir, "/.kaffeine/")
 			break
 		}
@@ -47,7 +88,7 @@ func GetDirectory() (dir string, err error) {
 	}
 
 	if dir == "" {
-		dir = filepath.Clean(filepath.Join(wd, "/.kaffeine/"))
+		return filepath.Clean(filepath.Join(wd, "/.kaffeine/")), ErrNoDirectory
 	}
 
 	return dir, err
@@ -55,63 +96,166 @@ func GetDirectory() (dir string, err error) {
 
 // Returns a new KRM Function Manager struct.
 // If directory == "", it will use GetDirectory() to find where to store its
-// files.
-func NewFunctionManager(directory string) *FunctionManager {
+// files, and fails if there is none.
+func NewFunctionManager(directory string) (*FunctionManager, error) {
+	return newFunctionManager(directory, false)
+}
+
+// Returns a new KRM Function Manager struct that refuses to Save and never
+// creates any files. Meant for commands that only query, so that a transient
+// failure can not change what is on disk.
+func NewReadOnlyFunctionManager(directory string) (*FunctionManager, error) {
+	return newFunctionManager(directory, true)
+}
+
+func newFunctionManager(directory string, readOnly bool) (*FunctionManager, error) {
 	if directory == "" {
-		directory, _ = GetDirectory()
+		var err error
+		directory, err = GetDirectory()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	os.MkdirAll(directory, os.ModePerm)
-
-	fm := FunctionManager{}
+	fm := FunctionManager{ReadOnly: readOnly}
 
 	fm.Directory = directory
 	catman := MakeCatalogManager(directory)
 	fm.CatMan = &catman
 	cfg := MakeConfig(directory)
 	fm.Cfg = &cfg
+	fm.Store = MakeBinaryStore(fm.Cfg.GetStoreDirectory())
 
-	for _, uri := range fm.Cfg.Catalogs {
-		err := fm.CatMan.AddCatalogFromUri(uri)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v", err)
-		}
+	client, err := MakeHTTPClient(fm.Cfg)
+	if err != nil {
+		return nil, err
+	}
+	fm.CatMan.Client = client
+	fm.Store.Client = client
+
+	for _, err := range fm.LoadCatalogs() {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 	}
-	fm.Cfg.Catalogs = maps.Keys(fm.CatMan.Catalogs)
 
 	// LIST CACHE
 	// n    n     - Do nothing
 	// n    Y     - Remove from cache
 	// Y    n     - Attempt to fetch catalog and load into memory
 	// Y    Y     - Load into memory
-	os.MkdirAll(filepath.Join(fm.Directory, "functions"), os.ModePerm)
 	fm.Installed = map[string]FunctionDefinition{}
+	fm.Unresolved = map[string]string{}
 	for _, fname := range fm.Cfg.Dependencies.KrmFunctions {
 		_, err := fm.AddFunctionDefinition(fname)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v", err)
+			group, name, _ := ToGroupNameVersion(fname)
+			if _, ok := fm.Installed[group+"/"+name]; !ok {
+				fm.Unresolved[group+"/"+name] = fname
+			}
+			fmt.Fprintf(os.Stderr, "could not resolve function '%s': %v\n", fname, err)
 			continue
 		}
 	}
 
-	return &fm
+	return &fm, nil
 }
 
-func (fm *FunctionManager) Save() error {
-	fm.UpdateConfig()
+// Brings the CatalogManager in line with the catalogs of the config: loads the
+// enabled catalogs, unloads the disabled ones and orders them by priority.
+// Catalogs that fail to load are remembered, so that they are not dropped from
+// the config.
+func (fm *FunctionManager) LoadCatalogs() (errs []error) {
+	for _, cc := range fm.Cfg.Catalogs {
+		fm.CatMan.Settings[cc.Uri] = cc
+	}
+
+	enabled := fm.Cfg.CatalogUris()
+	for _, uri := range append([]string{}, fm.CatMan.Priority...) {
+		if !slices.Contains(enabled, uri) {
+			fm.CatMan.RemoveCatalog(uri)
+		}
+	}
+
+	for _, uri := range enabled {
+		_, loaded := fm.CatMan.Catalogs[uri]
+		_, unavailable := fm.CatMan.Unavailable[uri]
+		if loaded || unavailable {
+			continue
+		}
+
+		fm.CatMan.Priority = append(fm.CatMan.Priority, uri)
+		if err := fm.CatMan.AddCatalogFromUri(uri); err != nil {
+			fm.CatMan.Unavailable[uri] = err
+			errs = append(errs, fmt.Errorf("could not load catalog '%s': %w", uri, err))
+		}
+	}
+
+	fm.CatMan.Reorder(enabled)
+	return errs
+}
+
+// Adds the catalog to the config and loads it, see Config.AddCatalog
+func (fm *FunctionManager) AddCatalog(cc CatalogConfig) error {
+	if err := fm.Cfg.AddCatalog(cc); err != nil {
+		return err
+	}
+
+	cc = fm.Cfg.Catalogs[len(fm.Cfg.Catalogs)-1]
+	if errs := fm.LoadCatalogs(); len(errs) > 0 {
+		fm.Cfg.RemoveCatalog(cc.Uri)
+		fm.LoadCatalogs()
+		delete(fm.CatMan.Settings, cc.Uri)
+		return errs[0]
+	}
 
-	fnBakDirectory := filepath.Join(fm.Directory, "functions.bak")
-	os.RemoveAll(fnBakDirectory)
-	err := os.Rename(filepath.Join(fm.Directory, "functions"), fnBakDirectory)
+	return nil
+}
+
+// Removes the catalog with the given name or uri from the config and unloads
+// it
+func (fm *FunctionManager) RemoveCatalog(nameOrUri string) (cc CatalogConfig, err error) {
+	cc, err = fm.Cfg.RemoveCatalog(nameOrUri)
 	if err != nil {
+		return
+	}
+
+	fm.LoadCatalogs()
+	delete(fm.CatMan.Settings, cc.Uri)
+	return cc, nil
+}
+
+func (fm *FunctionManager) Save() error {
+	if fm.ReadOnly {
+		return ErrReadOnly
+	}
+
+	if err := os.MkdirAll(fm.Directory, os.ModePerm); err != nil {
 		return err
 	}
 
+	// A frozen install reproduces the config, so it is not rewritten
+	if !fm.Frozen {
+		fm.UpdateConfig()
+	}
+
+	// Functions whose version and binary did not change are left alone, so
+	// nothing is downloaded unless something was installed or updated
 	for _, groupName := range maps.Keys(fm.Installed) {
-		fm.SaveFunctionDefinition(groupName)
+		if fm.isFunctionSaved(fm.Installed[groupName]) {
+			continue
+		}
+		if _, err := fm.SaveFunctionDefinition(groupName); err != nil {
+			return err
+		}
 	}
 
-	os.RemoveAll(fnBakDirectory)
+	// Written once the binaries are downloaded, to lock their digests
+	if err := fm.saveLockfile(); err != nil {
+		return err
+	}
+
+	if err := fm.removeUnusedFunctionDefinitions(); err != nil {
+		return err
+	}
 
 	if installedCatalog, err := fm.GenerateInstalledCatalog(); err != nil {
 		return err
@@ -119,8 +263,10 @@ func (fm *FunctionManager) Save() error {
 		os.WriteFile(filepath.Join(fm.Directory, "installed.yaml"), installedCatalog, os.ModePerm)
 	}
 
-	if err := fm.Cfg.Save(); err != nil {
-		return err
+	if !fm.Frozen {
+		if err := fm.Cfg.Save(); err != nil {
+			return err
+		}
 	}
 	if err := fm.CatMan.Save(); err != nil {
 		return err
@@ -143,36 +289,47 @@ func (fm *FunctionManager) SaveFunctionDefinition(fname string) (fd FunctionDefi
 
 	os.MkdirAll(fnDir, os.ModePerm)
 
-	// FIXME: Better binary management
 	if len(fd.Versions[0].Runtime.Exec.Platforms) > 0 {
-		// fmt.Println("HEEERE")
-		oldUri := fd.Versions[0].Runtime.Exec.Platforms[0].Uri
-		resp, err := http.Get(oldUri)
+		target := fm.platformFor(fd)
+		i, err := fd.Versions[0].Runtime.Exec.GetPlatform(target)
 		if err != nil {
-			// fmt.Println(err)
-			return fd, err
+			return fd, fmt.Errorf("function '%s': %w", groupName, err)
 		}
-		defer resp.Body.Close()
 
-		binFile := filepath.Join(fnDir, fd.Names.Kind+filepath.Ext(oldUri))
-		// fmt.Println(binFile)
-		out, err := os.Create(binFile)
-		if err != nil {
-			return fd, err
+		platform := fd.Versions[0].Runtime.Exec.Platforms[i]
+		oldUri := platform.Uri
+
+		// Only download binaries that are not in the store yet
+		digest := fm.Store.Lookup(fd, platform)
+		if digest == "" {
+			expectedSha256 := platform.Sha256
+			if fm.SkipDigestVerification {
+				expectedSha256 = ""
+			} else if !IsValidSha256(expectedSha256) {
+				return fd, fmt.Errorf("function '%s' declares invalid sha256 '%s' for '%s' (use --insecure-skip-digest to install anyway)", groupName, expectedSha256, oldUri)
+			}
+
+			digest, err = fm.Store.Add(oldUri, expectedSha256)
+			if err != nil {
+				return fd, fmt.Errorf("could not download binary of function '%s': %w", groupName, err)
+			}
 		}
-		defer out.Close()
 
-		_, err = io.Copy(out, resp.Body)
+		binFile, err := fm.Store.Link(digest, filepath.Join(fnDir, fd.Names.Kind), platform.Bin)
 		if err != nil {
-			return fd, err
+			return fd, fmt.Errorf("could not install binary of function '%s': %w", groupName, err)
 		}
 
+		fd.Metadata.Annotations[BinarySha256] = digest
+		fd.Metadata.Annotations[Platform] = target
 		fd.Metadata.Annotations[OriginalBinaryLocation] = oldUri
 		cpy := make([]FunctionRuntimePlatform, len(fd.Versions[0].Runtime.Exec.Platforms))
 		copy(cpy, fd.Versions[0].Runtime.Exec.Platforms)
-		cpy[0].Uri = "file://" + binFile
+		cpy[i].Uri = "file://" + binFile
 		fd.Metadata.Annotations[LocalBinaryLocation] = "file://" + binFile
-		fd.Versions[0].Runtime.Exec.Platforms = cpy
+		version := fd.Versions[0]
+		version.Runtime.Exec.Platforms = cpy
+		fd.Versions = []FunctionVersion{version}
 	}
 
 	b, err := yaml.Marshal(fd)
@@ -184,6 +341,110 @@ func (fm *FunctionManager) SaveFunctionDefinition(fname string) (fd FunctionDefi
 	return fd, nil
 }
 
+// Reports whether the function is saved with the same version, runtime and
+// binary, in which case SaveFunctionDefinition has nothing to do
+func (fm *FunctionManager) isFunctionSaved(fd FunctionDefinition) bool {
+	b, err := os.ReadFile(filepath.Join(fm.Directory, "functions", fd.Group, fd.Names.Kind+".yaml"))
+	if err != nil {
+		return false
+	}
+
+	saved := FunctionDefinition{}
+	if err := yaml.Unmarshal(b, &saved); err != nil || len(saved.Versions) != 1 || saved.Metadata == nil {
+		return false
+	}
+	restoreOriginalBinaryUri(&saved)
+
+	if saved.RequestedVersion() != fd.RequestedVersion() {
+		return false
+	}
+
+	savedVersions, err := yaml.Marshal(saved.Versions)
+	if err != nil {
+		return false
+	}
+	versions, err := yaml.Marshal(fd.Versions)
+	if err != nil || !bytes.Equal(savedVersions, versions) {
+		return false
+	}
+
+	if len(fd.Versions[0].Runtime.Exec.Platforms) == 0 {
+		return true
+	}
+	if saved.Metadata.Annotations[Platform] != fm.platformFor(fd) {
+		return false
+	}
+	_, err = saved.LocalBinaryPath()
+	return err == nil
+}
+
+// Removes the saved definitions and binary links of functions that are no
+// longer installed
+func (fm *FunctionManager) removeUnusedFunctionDefinitions() error {
+	saved, err := fm.savedFunctionDefinitions()
+	if err != nil {
+		return err
+	}
+
+	for groupName := range saved {
+		_, installed := fm.Installed[groupName]
+		_, unresolved := fm.Unresolved[groupName]
+		if installed || unresolved {
+			continue
+		}
+
+		path := filepath.Join(fm.Directory, "functions", filepath.FromSlash(groupName))
+		if err := os.Remove(path + ".yaml"); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the function definitions saved in the functions directory, keyed by
+// their GroupName
+func (fm *FunctionManager) savedFunctionDefinitions() (map[string]FunctionDefinition, error) {
+	fnDir := filepath.Join(fm.Directory, "functions")
+
+	saved := map[string]FunctionDefinition{}
+	err := filepath.WalkDir(fnDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Binary directories sit next to their definitions
+			if _, err := os.Stat(path + ".yaml"); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".yaml" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(fnDir, path)
+		if err != nil {
+			return err
+		}
+
+		fd := FunctionDefinition{}
+		if b, err := os.ReadFile(path); err == nil {
+			yaml.Unmarshal(b, &fd)
+		}
+		saved[filepath.ToSlash(strings.TrimSuffix(rel, ".yaml"))] = fd
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
 func (fm *FunctionManager) AddFunctionDefinition(fname string) (fn FunctionDefinition, err error) {
 	group, name, _ := ToGroupNameVersion(fname)
 	groupName := group + "/" + name
@@ -204,25 +465,25 @@ func (fm *FunctionManager) AddFunctionDefinition(fname string) (fn FunctionDefin
 		return fn, fmt.Errorf("function '%s' already installed", fn.GroupName())
 	}
 
-	// FIXME: Better binary management
-	if val, ok := fn.Metadata.Annotations[OriginalBinaryLocation]; ok && val != "" {
-		cpy := make([]FunctionRuntimePlatform, len(fn.Versions[0].Runtime.Exec.Platforms))
-		copy(cpy, fn.Versions[0].Runtime.Exec.Platforms)
-		fn.Metadata.Annotations[LocalBinaryLocation] = cpy[0].Uri
-		cpy[0].Uri = val
-		fn.Versions[0].Runtime.Exec.Platforms = cpy
-	}
+	restoreOriginalBinaryUri(&fn)
 
+	delete(fm.Unresolved, fn.GroupName())
 	fm.Installed[fn.GroupName()] = fn
 
 	return fn, nil
 }
 
+// Removes the installed function. A dependency that could not be resolved is
+// removed as well, returning a zero FunctionDefinition.
 func (fm *FunctionManager) RemoveFunctionDefinition(fname string) (oldFd FunctionDefinition, err error) {
 	group, name, _ := ToGroupNameVersion(fname)
 	groupName := group + "/" + name
 
-	if _, ok := fm.Installed[fname]; !ok {
+	if _, ok := fm.Unresolved[groupName]; ok {
+		delete(fm.Unresolved, groupName)
+		return FunctionDefinition{}, nil
+	}
+	if _, ok := fm.Installed[groupName]; !ok {
 		return oldFd, fmt.Errorf("function with name '%s' not installed", groupName)
 	}
 
@@ -234,6 +495,7 @@ func (fm *FunctionManager) RemoveFunctionDefinition(fname string) (oldFd Functio
 
 // returns a function with a single version
 func (fm *FunctionManager) GetCachedFunctionDefinition(fname string) (fn FunctionDefinition, err error) {
+	alias, _ := SplitCatalogAlias(fname)
 	group, name, version := ToGroupNameVersion(fname)
 	fnDir := filepath.Join(fm.Directory, "functions", group)
 	fnFile := name + ".yaml"
@@ -256,25 +518,31 @@ func (fm *FunctionManager) GetCachedFunctionDefinition(fname string) (fn Functio
 	if len(fn.Versions) != 1 {
 		return fn, fmt.Errorf("cached function definition for '%s' has does not have exactly 1 version", fname)
 	}
-
-	if fn.Metadata == nil {
-		fn.Metadata = &v1.ObjectMeta{Annotations: map[string]string{}}
+	if fn.CatalogAlias() != alias {
+		return fn, fmt.Errorf("cached function definition for '%s' comes from another catalog", fname)
 	}
 
-	if version != "" {
-		if fn.Versions[0].Name != version {
+	if IsVersionConstraint(version) {
+		c, err := ParseConstraint(version)
+		if err != nil {
+			return fn, err
+		}
+		if !c.Check(fn.Versions[0].Name, fm.AllowPrerelease) {
+			return fn, fmt.Errorf("cached function definition for '%s' does not satisfy '%s'", fname, version)
+		}
+	} else if version != "" {
+		if !VersionNamesEqual(fn.Versions[0].Name, version) {
 			return fn, fmt.Errorf("cached function definition for '%s' does not have version", version)
 		}
-		fn.Metadata.Annotations[IgnoreAutoUpdates] = "true"
-	} else {
-		fn.Metadata.Annotations[IgnoreAutoUpdates] = "false"
 	}
+	setRequestedVersion(&fn, version)
 
 	return
 }
 
 // returns a function with a single version
 func (fm *FunctionManager) GetExternalFunctionDefinition(fname string) (fn FunctionDefinition, err error) {
+	alias, _ := SplitCatalogAlias(fname)
 	_, _, version := ToGroupNameVersion(fname)
 	result, err := fm.CatMan.Search(fname, false)
 	if err != nil {
@@ -289,24 +557,29 @@ func (fm *FunctionManager) GetExternalFunctionDefinition(fname string) (fn Funct
 
 	fn = result[0]
 
-	if fn.Metadata == nil {
-		fn.Metadata = &v1.ObjectMeta{Annotations: map[string]string{}}
-	}
-
 	var v FunctionVersion
 
 	if version == "" {
-		v = fn.GetHighestVersion()
-		fn.Metadata.Annotations[IgnoreAutoUpdates] = "false"
+		v = fn.GetHighestVersion(fm.AllowPrerelease)
+	} else if IsVersionConstraint(version) {
+		var c Constraint
+		c, err = ParseConstraint(version)
+		if err != nil {
+			return
+		}
+		v, err = fn.GetHighestMatchingVersion(c, fm.AllowPrerelease)
+		if err != nil {
+			return
+		}
 	} else {
 		v, err = result[0].GetVersion(version)
 		if err != nil {
 			return
 		}
-
-		fn.Metadata.Annotations[IgnoreAutoUpdates] = "true"
 	}
 
+	setRequestedVersion(&fn, version)
+	setCatalogAlias(&fn, alias)
 	fn.Versions = []FunctionVersion{v}
 
 	return
@@ -323,8 +596,17 @@ func (fm *FunctionManager) UpdateFunctionDefinition(fname string) (oldFn Functio
 		return FunctionDefinition{}, nil
 	}
 
+	// Stay within the range the function was installed with
+	query := oldFn.GroupName()
+	if version := oldFn.RequestedVersion(); version != "" {
+		query += "@" + version
+	}
+	if alias := oldFn.CatalogAlias(); alias != "" {
+		query = alias + "::" + query
+	}
+
 	var newFn FunctionDefinition
-	newFn, err = fm.GetExternalFunctionDefinition(fname)
+	newFn, err = fm.GetExternalFunctionDefinition(query)
 	if err != nil {
 		fm.Installed[oldFn.GroupName()] = oldFn
 		return
@@ -346,52 +628,169 @@ func (fm *FunctionManager) UpdateAllFunctionDefinitions() (oldFns []FunctionDefi
 }
 
 func (fm *FunctionManager) SearchFunctionDefintions(fname string) (result []byte, err error) {
-	fds, err := fm.CatMan.Search(fname, true)
+	fc, err := fm.SearchCatalog(fname)
 	if err != nil {
 		return nil, err
 	}
-	fc := MakeFunctionCatalog("Search results for '" + fname + "'")
-	fc.Spec.KrmFunctions = append(fc.Spec.KrmFunctions, fds...)
 	return yaml.Marshal(fc)
 }
 
-func (fm *FunctionManager) GenerateInstalledCatalog() (result []byte, err error) {
-	fc := MakeFunctionCatalog("kaffeine Managed Functions")
-	for _, fn := range fm.Installed {
-		// FIXME: Better binary management
-		if val, ok := fn.Metadata.Annotations[LocalBinaryLocation]; ok && val != "" {
-			fn.Versions[0].Runtime.Exec.Platforms[0].Uri = val
-		}
-		fc.Spec.KrmFunctions = append(fc.Spec.KrmFunctions, fn)
-	}
-	result, err = yaml.Marshal(fc)
+// Returns the functions matching the query as a catalog, see CatalogManager.Query
+func (fm *FunctionManager) SearchCatalog(fname string) (fc FunctionCatalog, err error) {
+	fds, err := fm.CatMan.Query(fname)
 	if err != nil {
-		return result, err
+		return fc, err
 	}
+	fc = MakeFunctionCatalog("Search results for '" + fname + "'")
+	fc.Spec.KrmFunctions = append([]FunctionDefinition{}, fds...)
+	return fc, nil
+}
+
+func (fm *FunctionManager) GenerateInstalledCatalog() (result []byte, err error) {
+	return yaml.Marshal(fm.InstalledCatalog())
+}
 
-	// FIXME: Better binary management
-	for _, fn := range fm.Installed {
-		if val, ok := fn.Metadata.Annotations[OriginalBinaryLocation]; ok && val != "" {
-			fn.Versions[0].Runtime.Exec.Platforms[0].Uri = val
+// Returns the installed functions as a catalog, sorted by GroupName. Their
+// binaries point to the local copies.
+func (fm *FunctionManager) InstalledCatalog() FunctionCatalog {
+	fc := MakeFunctionCatalog("kaffeine Managed Functions")
+	fc.Spec.KrmFunctions = []FunctionDefinition{}
+	groupNames := maps.Keys(fm.Installed)
+	sort.Strings(groupNames)
+	for _, groupName := range groupNames {
+		fn := fm.Installed[groupName]
+		if i := fn.installedPlatformIndex(); i >= 0 {
+			version := fn.Versions[0]
+			version.Runtime.Exec.Platforms = make([]FunctionRuntimePlatform, len(fn.Versions[0].Runtime.Exec.Platforms))
+			copy(version.Runtime.Exec.Platforms, fn.Versions[0].Runtime.Exec.Platforms)
+			version.Runtime.Exec.Platforms[i].Uri = fn.Metadata.Annotations[LocalBinaryLocation]
+			fn.Versions = []FunctionVersion{version}
 		}
+		fc.Spec.KrmFunctions = append(fc.Spec.KrmFunctions, fn)
 	}
 
-	return result, err
+	return fc
 }
 
 func (fm *FunctionManager) UpdateConfig() (err error) {
-	fm.Cfg.Catalogs = maps.Keys(fm.CatMan.Catalogs)
+	// Catalogs removed from the CatalogManager are removed from the config, and
+	// those added to it are added with their settings
+	catalogs := []CatalogConfig{}
+	for _, cc := range fm.Cfg.Catalogs {
+		_, loaded := fm.CatMan.Catalogs[cc.Uri]
+		_, unavailable := fm.CatMan.Unavailable[cc.Uri]
+		if loaded || unavailable || !cc.IsEnabled() {
+			catalogs = append(catalogs, cc)
+		}
+	}
+	fm.Cfg.Catalogs = catalogs
+	for _, uri := range fm.CatMan.Priority {
+		if fm.Cfg.catalogIndex(uri) < 0 {
+			cc := fm.CatMan.Settings[uri]
+			cc.Uri = uri
+			fm.Cfg.AddCatalog(cc)
+		}
+	}
 
-	fm.Cfg.Dependencies.KrmFunctions = make([]string, 0)
+	fnames := map[string]string{}
+	for groupName, fname := range fm.Unresolved {
+		fnames[groupName] = fname
+	}
 	for groupName, fd := range fm.Installed {
 		fname := groupName
-		if fd.Metadata != nil {
-			if val, ok := fd.Metadata.Annotations[IgnoreAutoUpdates]; ok && val == "true" {
-				fname = fname + "@" + fd.Versions[0].Name
-			}
+		if version := fd.RequestedVersion(); version != "" {
+			fname = fname + "@" + version
+		}
+		if alias := fd.CatalogAlias(); alias != "" {
+			fname = alias + "::" + fname
 		}
-		fm.Cfg.Dependencies.KrmFunctions = append(fm.Cfg.Dependencies.KrmFunctions, fname)
+		fnames[groupName] = fname
 	}
+	fm.Cfg.Dependencies.KrmFunctions = keepOrder(fm.Cfg.Dependencies.KrmFunctions, fnames, func(fname string) string {
+		group, name, _ := ToGroupNameVersion(fname)
+		return group + "/" + name
+	})
 
 	return nil
 }
+
+// Returns the values of items, ordered like their keys appear in previous.
+// Keys that are not in previous come last, sorted.
+func keepOrder(previous []string, items map[string]string, key func(string) string) []string {
+	result := make([]string, 0, len(items))
+	seen := map[string]bool{}
+	for _, p := range previous {
+		k := key(p)
+		if v, ok := items[k]; ok && !seen[k] {
+			result = append(result, v)
+			seen[k] = true
+		}
+	}
+
+	rest := []string{}
+	for k := range items {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	for _, k := range rest {
+		result = append(result, items[k])
+	}
+
+	return result
+}
+
+// Sets the annotations recording how the version of the function was
+// requested, copying the metadata first since it may be shared with a catalog.
+// See FunctionDefinition.RequestedVersion
+func setRequestedVersion(fn *FunctionDefinition, version string) {
+	if fn.Metadata == nil {
+		fn.Metadata = &v1.ObjectMeta{}
+	} else {
+		fn.Metadata = fn.Metadata.DeepCopy()
+	}
+	if fn.Metadata.Annotations == nil {
+		fn.Metadata.Annotations = map[string]string{}
+	}
+
+	delete(fn.Metadata.Annotations, VersionConstraint)
+	if IsVersionConstraint(version) {
+		fn.Metadata.Annotations[IgnoreAutoUpdates] = "false"
+		fn.Metadata.Annotations[VersionConstraint] = version
+	} else if version != "" {
+		fn.Metadata.Annotations[IgnoreAutoUpdates] = "true"
+	} else {
+		fn.Metadata.Annotations[IgnoreAutoUpdates] = "false"
+	}
+}
+
+// Pins the function to the catalog with the given alias, or lets it come from
+// any catalog if alias is "". Expects the annotations set by
+// setRequestedVersion.
+func setCatalogAlias(fn *FunctionDefinition, alias string) {
+	if alias == "" {
+		delete(fn.Metadata.Annotations, CatalogAlias)
+	} else {
+		fn.Metadata.Annotations[CatalogAlias] = alias
+	}
+}
+
+// Saved definitions point to the local binary, restore the original uri and
+// remember the local one in the LocalBinaryLocation annotation
+func restoreOriginalBinaryUri(fn *FunctionDefinition) {
+	val, ok := fn.Metadata.Annotations[OriginalBinaryLocation]
+	if !ok || val == "" {
+		return
+	}
+
+	if i := fn.installedPlatformIndex(); i >= 0 {
+		cpy := make([]FunctionRuntimePlatform, len(fn.Versions[0].Runtime.Exec.Platforms))
+		copy(cpy, fn.Versions[0].Runtime.Exec.Platforms)
+		fn.Metadata.Annotations[LocalBinaryLocation] = cpy[i].Uri
+		cpy[i].Uri = val
+		version := fn.Versions[0]
+		version.Runtime.Exec.Platforms = cpy
+		fn.Versions = []FunctionVersion{version}
+	}
+}
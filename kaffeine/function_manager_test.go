@@ -0,0 +1,276 @@
+package kaffeine
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func makeTestFunctionManager(t *testing.T, versions ...string) *FunctionManager {
+	t.Helper()
+	catman := MakeCatalogManager(t.TempDir())
+	cat := MakeFunctionCatalog("test")
+	fd := FunctionDefinition{Group: "example.com"}
+	fd.Names.Kind = "Logger"
+	for _, v := range versions {
+		fd.Versions = append(fd.Versions, FunctionVersion{Name: v})
+	}
+	cat.Spec.KrmFunctions = append(cat.Spec.KrmFunctions, fd)
+	if err := catman.AddCatalogFromStruct("file:///test.yaml", cat); err != nil {
+		t.Fatal(err)
+	}
+
+	return &FunctionManager{CatMan: &catman, Cfg: &Config{}, Store: MakeBinaryStore(t.TempDir()), Installed: map[string]FunctionDefinition{}}
+}
+
+func TestUpdateWithinConstraint(t *testing.T) {
+	var tests = []struct {
+		fname, installed, updated, config string
+	}{
+		{"example.com/Logger", "v2.0.0", "v3.0.0", "example.com/Logger"},
+		{"example.com/Logger@v1.0.1", "v1.0.1", "v1.0.1", "example.com/Logger@v1.0.1"},
+		{"example.com/Logger@^1.0", "v1.2.0", "v1.3.0", "example.com/Logger@^1.0"},
+		{"example.com/Logger@~1.0.1", "v1.0.2", "v1.0.3", "example.com/Logger@~1.0.1"},
+		{"example.com/Logger@>=1.1.0 <2.0.0", "v1.2.0", "v1.3.0", "example.com/Logger@>=1.1.0 <2.0.0"},
+	}
+
+	for _, test := range tests {
+		fm := makeTestFunctionManager(t, "v1.0.1", "v1.0.2", "v1.2.0", "v2.0.0")
+		fd, err := fm.GetExternalFunctionDefinition(test.fname)
+		if err != nil {
+			t.Errorf("%s: %v", test.fname, err)
+			continue
+		}
+		fm.Installed[fd.GroupName()] = fd
+		if fd.Versions[0].Name != test.installed {
+			t.Errorf("%s: installed %s, want %s", test.fname, fd.Versions[0].Name, test.installed)
+		}
+
+		fm.UpdateConfig()
+		if fm.Cfg.Dependencies.KrmFunctions[0] != test.config {
+			t.Errorf("%s: config has %s, want %s", test.fname, fm.Cfg.Dependencies.KrmFunctions[0], test.config)
+		}
+
+		// New versions are published, both inside and outside the ranges
+		fn := fm.CatMan.Functions["example.com/Logger"]
+		fn.Versions = append(fn.Versions, FunctionVersion{Name: "v1.0.3"}, FunctionVersion{Name: "v1.3.0"}, FunctionVersion{Name: "v3.0.0"})
+		fm.CatMan.Functions["example.com/Logger"] = fn
+
+		if _, errs := fm.UpdateAllFunctionDefinitions(); errs[0] != nil {
+			t.Errorf("%s: %v", test.fname, errs[0])
+		}
+		if got := fm.Installed["example.com/Logger"].Versions[0].Name; got != test.updated {
+			t.Errorf("%s: updated to %s, want %s", test.fname, got, test.updated)
+		}
+	}
+}
+
+func TestPrereleaseWithinConstraint(t *testing.T) {
+	var tests = []struct {
+		fname string
+		pre   bool
+		want  string
+	}{
+		{"example.com/Logger@^1.0", false, "v1.1.0"},
+		{"example.com/Logger@^1.0", true, "v1.2.0-rc.1"},
+		{"example.com/Logger@>=1.2.0-rc.1 <2.0.0", false, "v1.2.0-rc.1"},
+		{"example.com/Logger", true, "v2.0.0-rc.1"},
+		{"example.com/Logger@v1.2.0-rc.1", false, "v1.2.0-rc.1"},
+	}
+
+	for _, test := range tests {
+		fm := makeTestFunctionManager(t, "v1.0.0", "v1.1.0", "v1.2.0-rc.1", "v2.0.0-rc.1")
+		fm.AllowPrerelease = test.pre
+		fd, err := fm.GetExternalFunctionDefinition(test.fname)
+		if err != nil {
+			t.Errorf("%s (pre %v): %v", test.fname, test.pre, err)
+			continue
+		}
+		if fd.Versions[0].Name != test.want {
+			t.Errorf("%s (pre %v): got %s, want %s", test.fname, test.pre, fd.Versions[0].Name, test.want)
+		}
+	}
+}
+
+func TestSaveFunctionDefinitionPlatform(t *testing.T) {
+	dir := t.TempDir()
+	fd := FunctionDefinition{Group: "example.com", Versions: []FunctionVersion{{Name: "v1.0.0"}}}
+	fd.Names.Kind = "Echo"
+	for _, platform := range []string{"darwin/arm64", "linux/amd64", "linux/arm64"} {
+		goos, goarch, _ := ParsePlatform(platform)
+		content := []byte("#!/bin/sh\necho " + platform + "\n")
+		src := filepath.Join(dir, goos+"-"+goarch)
+		if err := os.WriteFile(src, content, 0755); err != nil {
+			t.Fatal(err)
+		}
+		fd.Versions[0].Runtime.Exec.Platforms = append(fd.Versions[0].Runtime.Exec.Platforms, FunctionRuntimePlatform{
+			Os: goos, Arch: goarch, Uri: "file://" + src, Sha256: fmt.Sprintf("%x", sha256.Sum256(content)),
+		})
+	}
+	setRequestedVersion(&fd, "")
+
+	store := MakeBinaryStore(t.TempDir())
+	for _, platform := range []string{"linux/arm64", "darwin/arm64"} {
+		fm := FunctionManager{Directory: t.TempDir(), Store: store, Platform: platform, Installed: map[string]FunctionDefinition{fd.GroupName(): fd}}
+		saved, err := fm.SaveFunctionDefinition(fd.GroupName())
+		if err != nil {
+			t.Fatalf("%s: %v", platform, err)
+		}
+		if saved.Metadata.Annotations[Platform] != platform {
+			t.Errorf("%s: recorded platform %s", platform, saved.Metadata.Annotations[Platform])
+		}
+
+		path, err := saved.LocalBinaryPath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := os.ReadFile(path)
+		if !strings.Contains(string(b), platform) {
+			t.Errorf("%s: downloaded wrong binary %q", platform, b)
+		}
+	}
+
+	fm := FunctionManager{Directory: t.TempDir(), Store: store, Platform: "windows/amd64", Installed: map[string]FunctionDefinition{fd.GroupName(): fd}}
+	if _, err := fm.SaveFunctionDefinition(fd.GroupName()); err == nil {
+		t.Errorf("expected error for platform without binary")
+	}
+}
+
+func TestSaveIncremental(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "fn")
+	content := []byte("#!/bin/sh\ncat\n")
+	os.WriteFile(src, content, 0755)
+
+	fm := makeTestFunctionManager(t, "v1.0.0")
+	fm.Directory = filepath.Join(dir, ".kaffeine")
+	fm.Cfg.FilePath = filepath.Join(fm.Directory, "config.yaml")
+	fm.CatMan.Directory = filepath.Join(fm.Directory, "catalogs")
+	os.MkdirAll(fm.CatMan.Directory, os.ModePerm)
+	fm.Store = MakeBinaryStore(filepath.Join(dir, "store"))
+
+	fn := fm.CatMan.Functions["example.com/Logger"]
+	fn.Versions[0].Runtime.Exec.Platforms = []FunctionRuntimePlatform{{
+		Os: "linux", Arch: "amd64", Uri: "file://" + src, Sha256: fmt.Sprintf("%x", sha256.Sum256(content)),
+	}}
+	fm.Platform = "linux/amd64"
+
+	if _, err := fm.AddFunctionDefinition("example.com/Logger"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fm.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	defFile := filepath.Join(fm.Directory, "functions", "example.com", "Logger.yaml")
+	before, err := os.Stat(defFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing changed, so nothing is downloaded or written again
+	os.Remove(src)
+	fm.Store = MakeBinaryStore(filepath.Join(dir, "empty-store"))
+	if err := fm.Save(); err != nil {
+		t.Fatalf("unchanged save tried to download: %v", err)
+	}
+	if after, err := os.Stat(defFile); err != nil || !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("unchanged definition was rewritten (%v)", err)
+	}
+
+	// Removed functions are cleaned up
+	if _, err := fm.RemoveFunctionDefinition("example.com/Logger"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fm.Save(); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{defFile, strings.TrimSuffix(defFile, ".yaml")} {
+		if _, err := os.Lstat(path); err == nil {
+			t.Errorf("%s not removed", path)
+		}
+	}
+}
+
+func TestReadOnlyFunctionManager(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".kaffeine")
+	t.Setenv("KAFFEINE_STORE", t.TempDir())
+
+	fm, err := NewReadOnlyFunctionManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fm.Save(); err != ErrReadOnly {
+		t.Errorf("Save returned %v, want ErrReadOnly", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("read-only function manager created %s", dir)
+	}
+}
+
+func TestSaveKeepsUnavailableDependencies(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("KAFFEINE_STORE", t.TempDir())
+	t.Setenv("KAFFEINE_GLOBAL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+
+	catalog := filepath.Join(dir, "catalog.yaml")
+	if err := os.WriteFile(catalog, []byte("apiVersion: config.kubernetes.io/v1alpha1\nkind: FunctionCatalog\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := Config{FilePath: filepath.Join(dir, "config.yaml")}
+	config.Catalogs = []CatalogConfig{{Name: "missing", Uri: "file://" + filepath.Join(dir, "missing.yaml")}, {Name: "catalog", Uri: "file://" + catalog}}
+	config.Dependencies.KrmFunctions = []string{"example.com/Missing@v1.0.0"}
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	fm, err := NewFunctionManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fm.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := MakeConfig(dir)
+	if fmt.Sprint(saved.Catalogs) != fmt.Sprint(config.Catalogs) {
+		t.Errorf("catalogs are %v, want %v", saved.Catalogs, config.Catalogs)
+	}
+	if fmt.Sprint(saved.Dependencies.KrmFunctions) != fmt.Sprint(config.Dependencies.KrmFunctions) {
+		t.Errorf("functions are %v, want %v", saved.Dependencies.KrmFunctions, config.Dependencies.KrmFunctions)
+	}
+}
+
+func TestRemoveUnresolvedDependency(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("KAFFEINE_STORE", t.TempDir())
+	t.Setenv("KAFFEINE_GLOBAL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+
+	config := Config{FilePath: filepath.Join(dir, "config.yaml")}
+	config.Dependencies.KrmFunctions = []string{"example.com/Missing@v1.0.0"}
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	fm, err := NewFunctionManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, err := fm.RemoveFunctionDefinition("example.com/Missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fd.Versions) != 0 {
+		t.Errorf("got %v, want a zero FunctionDefinition", fd)
+	}
+	if err := fm.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if saved := MakeConfig(dir); len(saved.Dependencies.KrmFunctions) != 0 {
+		t.Errorf("functions are %v, want none", saved.Dependencies.KrmFunctions)
+	}
+}
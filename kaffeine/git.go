@@ -0,0 +1,193 @@
+package kaffeine
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// The schemes of catalogs in git repositories, see ParseGitReference
+var gitSchemes = []string{"git+https", "git+http", "git+file"}
+
+// The directory in the catalogs directory that git catalogs are cloned into
+const gitReposDirectory = ".repos"
+
+// A catalog file in a git repository, written as
+// "git+https://host/repo.git//path/catalog.yaml?ref=v1.2" or
+// "git+file:///path/to/repo//catalog.yaml". The ref is a branch, a tag or a
+// commit, HEAD if omitted.
+type GitReference struct {
+	// The url to clone, such as "https://host/repo.git"
+	Repository string
+	// Path of the catalog in the repository
+	Path string
+	Ref  string
+}
+
+func ParseGitReference(uri string) (ref GitReference, err error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return
+	}
+	if !isGitScheme(u.Scheme) {
+		return ref, fmt.Errorf("'%s' is not a git+https:// or git+file:// uri", uri)
+	}
+
+	i := strings.Index(u.Path, "//")
+	if i < 0 || strings.Trim(u.Path[i+2:], "/") == "" {
+		return ref, fmt.Errorf("git uri '%s' has no path to the catalog, as in 'repo.git//catalog.yaml'", uri)
+	}
+	ref.Path = strings.Trim(u.Path[i+2:], "/")
+	ref.Ref = u.Query().Get("ref")
+	if ref.Ref == "" {
+		ref.Ref = "HEAD"
+	}
+	if strings.HasPrefix(ref.Ref, "-") {
+		return ref, fmt.Errorf("invalid ref '%s' in git uri '%s'", ref.Ref, uri)
+	}
+
+	repo := *u
+	repo.Scheme = strings.TrimPrefix(u.Scheme, "git+")
+	repo.Path = u.Path[:i]
+	repo.RawPath = ""
+	repo.RawQuery = ""
+	repo.Fragment = ""
+	ref.Repository = repo.String()
+	return ref, nil
+}
+
+func isGitScheme(scheme string) bool {
+	for _, s := range gitSchemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// Reads the catalog of the git uri at the given commit. Without a commit, its
+// ref is fetched and the commit it points to is used. The repository is cloned
+// into the catalogs directory once, and only fetched from afterwards.
+func (cm *CatalogManager) readGitCatalog(uri string, commit string) (data []byte, resolved string, err error) {
+	ref, err := ParseGitReference(uri)
+	if err != nil {
+		return
+	}
+
+	dir := cm.gitRepoDirectory(ref)
+	if _, err = os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+			return
+		}
+		if _, err = runGit(dir, nil, "init", "--quiet", "--bare"); err != nil {
+			os.RemoveAll(dir)
+			return
+		}
+	} else if err != nil {
+		return
+	}
+
+	// A pinned commit is only fetched when the clone does not have it yet
+	want := ref.Ref
+	if commit != "" {
+		if _, err := runGit(dir, nil, "cat-file", "-e", commit+"^{commit}"); err == nil {
+			want = ""
+		} else {
+			want = commit
+		}
+	}
+	if want != "" {
+		if _, err = runGit(dir, cm.gitAuthConfig(ref, cm.Settings[uri].Auth), "fetch", "--quiet", "--no-tags", "--", ref.Repository, want); err != nil {
+			return
+		}
+		if commit == "" {
+			commit = "FETCH_HEAD"
+		}
+	}
+
+	out, err := runGit(dir, nil, "rev-parse", "--verify", "--quiet", commit+"^{commit}")
+	if err != nil {
+		return nil, "", fmt.Errorf("commit '%s' not found in '%s'", commit, ref.Repository)
+	}
+	resolved = strings.TrimSpace(string(out))
+
+	data, err = runGit(dir, nil, "show", resolved+":"+ref.Path)
+	if err != nil {
+		return nil, "", fmt.Errorf("'%s' not found in '%s' at commit %s", ref.Path, ref.Repository, resolved)
+	}
+	return data, resolved, nil
+}
+
+// Returns the directory the repository of the reference is cloned into
+func (cm *CatalogManager) gitRepoDirectory(ref GitReference) string {
+	return filepath.Join(cm.Directory, gitReposDirectory, fmt.Sprintf("%x", sha1.Sum([]byte(ref.Repository))))
+}
+
+// Removes the clones of repositories that no catalog comes from anymore
+func (cm *CatalogManager) pruneGitRepos() {
+	used := map[string]bool{}
+	for _, uri := range cm.Priority {
+		if ref, err := ParseGitReference(uri); err == nil {
+			used[cm.gitRepoDirectory(ref)] = true
+		}
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(cm.Directory, gitReposDirectory))
+	for _, entry := range entries {
+		if dir := filepath.Join(cm.Directory, gitReposDirectory, entry.Name()); !used[dir] {
+			os.RemoveAll(dir)
+		}
+	}
+}
+
+// Returns the git config passing the credentials of auth (see
+// HTTPClient.credential) to the repository, if any. Without credentials, git
+// falls back to its own credential helpers.
+func (cm *CatalogManager) gitAuthConfig(ref GitReference, auth string) []string {
+	u, err := url.Parse(ref.Repository)
+	if err != nil || u.Scheme == "file" || cm.Client == nil {
+		return nil
+	}
+
+	cred, ok := cm.Client.credential(auth, u)
+	if !ok {
+		return nil
+	}
+	header := "Authorization: Bearer " + cred.Token
+	if cred.Token == "" {
+		header = "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(cred.Username+":"+cred.Password))
+	}
+	return []string{"http.extraHeader=" + header}
+}
+
+// Runs git in the repository at dir with the given "key=value" config,
+// returning its output. Errors include what git printed.
+func runGit(dir string, config []string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	// Credentials are never prompted for, since kaffeine may run unattended.
+	// The config is passed in the environment to keep it out of the process
+	// list.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", fmt.Sprintf("GIT_CONFIG_COUNT=%d", len(config)))
+	for i, c := range config {
+		key, value, _ := strings.Cut(c, "=")
+		cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, key), fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, value))
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return out, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
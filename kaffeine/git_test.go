@@ -0,0 +1,148 @@
+package kaffeine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestParseGitReference(t *testing.T) {
+	var tests = []struct {
+		uri  string
+		want GitReference
+		err  bool
+	}{
+		{"git+https://github.com/org/repo.git//catalogs/konveyor.yaml?ref=v1.2", GitReference{"https://github.com/org/repo.git", "catalogs/konveyor.yaml", "v1.2"}, false},
+		{"git+file:///srv/repo//catalog.yaml", GitReference{"file:///srv/repo", "catalog.yaml", "HEAD"}, false},
+		{"git+https://github.com/org/repo.git", GitReference{}, true},
+		{"git+https://github.com/org/repo.git//", GitReference{}, true},
+		{"git+https://github.com/org/repo.git//catalog.yaml?ref=--upload-pack=x", GitReference{}, true},
+		{"https://github.com/org/repo.git//catalog.yaml", GitReference{}, true},
+	}
+
+	for _, test := range tests {
+		ref, err := ParseGitReference(test.uri)
+		if (err != nil) != test.err {
+			t.Errorf("%s: got error %v", test.uri, err)
+			continue
+		}
+		if err == nil && ref != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.uri, ref, test.want)
+		}
+	}
+}
+
+// A bare repository and a clone of it to commit catalogs from
+type testGitRepo struct {
+	t     *testing.T
+	bare  string
+	clone string
+}
+
+func newTestGitRepo(t *testing.T) *testGitRepo {
+	r := &testGitRepo{t: t, bare: t.TempDir(), clone: t.TempDir()}
+	r.git(r.bare, "init", "--quiet", "--bare", "--initial-branch=main")
+	r.git(r.clone, "init", "--quiet", "--initial-branch=main")
+	return r
+}
+
+func (r *testGitRepo) git(dir string, args ...string) string {
+	r.t.Helper()
+	out, err := runGit(dir, []string{"user.name=test", "user.email=test@example.com", "commit.gpgsign=false"}, args...)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Commits the catalog at path and pushes it, returning the commit
+func (r *testGitRepo) commit(path string, cat FunctionCatalog) string {
+	r.t.Helper()
+	b, _ := yaml.Marshal(cat)
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(r.clone, path)), os.ModePerm); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(r.clone, path), b, 0644); err != nil {
+		r.t.Fatal(err)
+	}
+	r.git(r.clone, "add", path)
+	r.git(r.clone, "commit", "--quiet", "-m", "Update "+path)
+	r.git(r.clone, "push", "--quiet", r.bare, "main")
+	return r.git(r.clone, "rev-parse", "HEAD")
+}
+
+func TestGitCatalog(t *testing.T) {
+	repo := newTestGitRepo(t)
+	first := repo.commit("catalogs/test.yaml", makeTestCatalog("test", "Test", "v1.0.0"))
+	repo.git(repo.clone, "tag", "v1")
+	repo.git(repo.clone, "push", "--quiet", repo.bare, "v1")
+	second := repo.commit("catalogs/test.yaml", makeTestCatalog("test", "Changed", "v1.0.0", "v2.0.0"))
+	repo.git(repo.clone, "reset", "--quiet", "--hard", first)
+	repo.git(repo.clone, "push", "--quiet", "--force", repo.bare, "main")
+
+	dir := t.TempDir()
+	uri := "git+file://" + repo.bare + "//catalogs/test.yaml?ref=main"
+	description := func(cm *CatalogManager) string {
+		return cm.Functions["konveyor.io/RouteFunction"].Description
+	}
+
+	cm := MakeCatalogManager(dir)
+	if err := cm.AddCatalogFromUri(uri); err != nil {
+		t.Fatal(err)
+	}
+	if cm.State[uri].Commit != first || description(&cm) != "Test" {
+		t.Errorf("got %s at %s, want the catalog at %s", description(&cm), cm.State[uri].Commit, first)
+	}
+	if err := cm.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without its cache, the catalog is still read at the pinned commit
+	repo.git(repo.clone, "reset", "--quiet", "--hard", second)
+	repo.git(repo.clone, "push", "--quiet", "--force", repo.bare, "main")
+	os.Remove(filepath.Join(dir, "catalogs", legacyCacheFile(uri)))
+	cm = MakeCatalogManager(dir)
+	if err := cm.AddCatalogFromUri(uri); err != nil {
+		t.Fatal(err)
+	}
+	if description(&cm) != "Test" {
+		t.Errorf("got %s, want the catalog at the pinned commit", description(&cm))
+	}
+
+	if _, err := cm.UpdateCatalog(uri); err != nil {
+		t.Fatal(err)
+	}
+	if cm.State[uri].Commit != second || description(&cm) != "Changed" {
+		t.Errorf("got %s at %s after update, want the catalog at %s", description(&cm), cm.State[uri].Commit, second)
+	}
+	if _, err := cm.UpdateCatalog(uri); err != nil || cm.State[uri].Commit != second {
+		t.Errorf("got %s (%v), want the commit to stay", cm.State[uri].Commit, err)
+	}
+
+	tagged := strings.Replace(uri, "ref=main", "ref=v1", 1)
+	if err := cm.AddCatalogFromUri(tagged); err != nil || cm.State[tagged].Commit != first {
+		t.Errorf("got commit %s (%v) for the tag, want %s", cm.State[tagged].Commit, err, first)
+	}
+
+	for _, uri := range []string{
+		"git+file://" + repo.bare + "//missing.yaml",
+		"git+file://" + repo.bare + "//catalogs/test.yaml?ref=unknown",
+		"git+file://" + filepath.Join(dir, "missing") + "//catalog.yaml",
+	} {
+		if _, err := cm.GetExternalCatalog(uri); err == nil {
+			t.Errorf("%s: expected error", uri)
+		}
+	}
+
+	cm.RemoveCatalog(uri)
+	cm.RemoveCatalog(tagged)
+	if err := cm.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "catalogs", gitReposDirectory)); len(entries) != 0 {
+		t.Errorf("got %d clones, want the unused ones removed", len(entries))
+	}
+}
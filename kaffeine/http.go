@@ -0,0 +1,275 @@
+package kaffeine
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Settings of the HTTP client that fetches catalogs and binaries
+type HTTPConfig struct {
+	// URL of the proxy to use. Defaults to the HTTPS_PROXY, HTTP_PROXY and
+	// NO_PROXY environment variables.
+	Proxy string `json:"proxy,omitempty"`
+	// PEM file of extra CAs to trust, on top of the system ones. Can be
+	// overridden with the KAFFEINE_CA_BUNDLE environment variable.
+	CABundle string `json:"caBundle,omitempty"`
+	// How long to wait for a connection and for the response headers, as a
+	// duration like "30s"
+	Timeout string `json:"timeout,omitempty"`
+	// How many times to retry a request that failed with a network error or a
+	// 429 or 5xx status. Defaults to 3.
+	Retries *int `json:"retries,omitempty"`
+}
+
+func (h HTTPConfig) String() string {
+	parts := []string{}
+	if h.Proxy != "" {
+		parts = append(parts, "proxy="+h.Proxy)
+	}
+	if h.CABundle != "" {
+		parts = append(parts, "caBundle="+h.CABundle)
+	}
+	if h.Timeout != "" {
+		parts = append(parts, "timeout="+h.Timeout)
+	}
+	if h.Retries != nil {
+		parts = append(parts, fmt.Sprintf("retries=%d", *h.Retries))
+	}
+	return strings.Join(parts, " ")
+}
+
+const (
+	defaultHTTPTimeout = 30 * time.Second
+	defaultHTTPRetries = 3
+	// Retry-After is not honored beyond this
+	maxRetryDelay = 30 * time.Second
+)
+
+// Error returned for responses that are not 2xx (or 304 for conditional
+// requests)
+type HTTPError struct {
+	Uri        string
+	StatusCode int
+	Status     string
+	// Headers of the response, such as the WWW-Authenticate challenge
+	Header http.Header
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("fetching '%s' failed: %s", e.Uri, e.Status)
+}
+
+// The client every catalog and binary is fetched with. The zero value uses
+// the default settings and no credentials.
+type HTTPClient struct {
+	Client *http.Client
+	// Credentials by key, see Config.Credentials
+	Credentials map[string]Credential
+	Retries     int
+	// Delay before the first retry, doubled for each one after it
+	Backoff time.Duration
+}
+
+// Creates the HTTP client described by the config. Fails if its proxy,
+// timeout or CA bundle are invalid.
+func MakeHTTPClient(cfg *Config) (*HTTPClient, error) {
+	settings := HTTPConfig{}
+	if cfg.HTTP != nil {
+		settings = *cfg.HTTP
+	}
+	if bundle := os.Getenv("KAFFEINE_CA_BUNDLE"); bundle != "" {
+		settings.CABundle = bundle
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	timeout := defaultHTTPTimeout
+	if settings.Timeout != "" {
+		d, err := time.ParseDuration(settings.Timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid http timeout '%s'", settings.Timeout)
+		}
+		timeout = d
+	}
+	// Downloads can take longer than this, so only waiting for the server is
+	// bounded
+	transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = timeout
+	transport.ResponseHeaderTimeout = timeout
+
+	if settings.Proxy != "" {
+		proxy, err := url.Parse(settings.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid http proxy '%s'", settings.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if settings.CABundle != "" {
+		pem, err := os.ReadFile(settings.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle '%s'", settings.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	retries := defaultHTTPRetries
+	if settings.Retries != nil {
+		retries = *settings.Retries
+	}
+
+	return &HTTPClient{
+		Client:      &http.Client{Transport: transport},
+		Credentials: cfg.Credentials,
+		Retries:     retries,
+		Backoff:     500 * time.Millisecond,
+	}, nil
+}
+
+// Returns the credentials for the key, or for the host of the uri if key is
+// empty. The KAFFEINE_AUTH_<KEY> environment variable, with the key in
+// uppercase and other characters than letters and digits replaced by "_",
+// takes precedence over the config. It holds either a token or
+// "username:password".
+func (c *HTTPClient) credential(key string, u *url.URL) (cred Credential, ok bool) {
+	keys := []string{key}
+	if key == "" {
+		keys = []string{u.Host, u.Hostname()}
+	}
+
+	for _, k := range keys {
+		if value := os.Getenv(credentialEnv(k)); value != "" {
+			if username, password, found := strings.Cut(value, ":"); found {
+				return Credential{Username: username, Password: password}, true
+			}
+			return Credential{Token: value}, true
+		}
+		if cred, ok := c.Credentials[k]; ok {
+			return cred, true
+		}
+	}
+
+	return cred, false
+}
+
+// Returns the environment variable holding the credentials for the key, see
+// credential
+func credentialEnv(key string) string {
+	return "KAFFEINE_AUTH_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, key)
+}
+
+// Sends a GET request for the uri with the credentials of auth (see
+// credential) and the given headers. An Authorization header takes precedence
+// over the credentials. Network errors and 429 or 5xx responses
+// are retried with backoff. Responses other than 2xx and 304 are returned as
+// an *HTTPError.
+func (c *HTTPClient) Get(uri string, auth string, header http.Header) (*http.Response, error) {
+	if c == nil {
+		c = &HTTPClient{}
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	cred, hasCred := c.credential(auth, u)
+	if auth != "" && !hasCred {
+		return nil, fmt.Errorf("no credentials '%s' for '%s' in config", auth, uri)
+	}
+
+	delay := c.Backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		switch {
+		case req.Header.Get("Authorization") != "":
+		case hasCred && cred.Token != "":
+			req.Header.Set("Authorization", "Bearer "+cred.Token)
+		case hasCred:
+			req.SetBasicAuth(cred.Username, cred.Password)
+		}
+
+		resp, err := client.Do(req)
+		retry := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if retry && attempt < c.Retries {
+			if resp != nil {
+				if after, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && after >= 0 {
+					delay = time.Duration(after) * time.Second
+					if delay > maxRetryDelay {
+						delay = maxRetryDelay
+					}
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			time.Sleep(delay)
+			delay *= 2
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotModified {
+			resp.Body.Close()
+			return nil, &HTTPError{Uri: uri, StatusCode: resp.StatusCode, Status: resp.Status, Header: resp.Header}
+		}
+		return resp, nil
+	}
+}
+
+// Opens the given "file://", "oci://" or HTTP(S) uri for reading, see Get and
+// OpenOCI
+func (c *HTTPClient) Open(uri string, auth string) (io.ReadCloser, error) {
+	u, err := url.ParseRequestURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "file":
+		return os.Open(u.Path)
+	case "oci":
+		body, _, err := c.OpenOCI(uri, auth, "")
+		return body, err
+	}
+
+	resp, err := c.Get(uri, auth, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,155 @@
+package kaffeine
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestHTTPClientGet(t *testing.T) {
+	var tests = []struct {
+		name        string
+		auth        string
+		credentials map[string]Credential
+		env         string
+		failures    int
+		status      int
+		want        string
+	}{
+		{"anonymous", "", nil, "", 0, 200, ""},
+		{"token by host", "", map[string]Credential{"127.0.0.1": {Token: "abc"}}, "", 0, 200, "Bearer abc"},
+		{"basic by auth", "mirror", map[string]Credential{"mirror": {Username: "bob", Password: "pw"}}, "", 0, 200, "Basic Ym9iOnB3"},
+		{"env over config", "mirror", map[string]Credential{"mirror": {Token: "abc"}}, "def", 0, 200, "Bearer def"},
+		{"missing auth", "other", nil, "", 0, 0, ""},
+		{"retried", "", nil, "", 2, 200, ""},
+		{"retries exhausted", "", nil, "", 3, 503, ""},
+		{"not found", "", nil, "", 0, 404, ""},
+	}
+
+	for _, test := range tests {
+		requests := 0
+		auth := ""
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			auth = r.Header.Get("Authorization")
+			switch {
+			case requests <= test.failures:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case test.status == 404:
+				http.NotFound(w, r)
+			default:
+				w.Write([]byte("ok"))
+			}
+		}))
+
+		t.Setenv("KAFFEINE_AUTH_MIRROR", test.env)
+		client := &HTTPClient{Credentials: test.credentials, Retries: 2}
+		resp, err := client.Get(server.URL, test.auth, nil)
+		server.Close()
+
+		if test.status == 0 {
+			if err == nil {
+				t.Errorf("%s: expected error", test.name)
+			}
+			continue
+		}
+
+		var httpErr *HTTPError
+		if test.status != 200 {
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != test.status {
+				t.Errorf("%s: got error %v, want status %d", test.name, err, test.status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		resp.Body.Close()
+		if auth != test.want || requests != test.failures+1 {
+			t.Errorf("%s: got authorization %q after %d requests, want %q", test.name, auth, requests, test.want)
+		}
+	}
+}
+
+func TestMakeHTTPClient(t *testing.T) {
+	retries := 0
+	var tests = []struct {
+		name string
+		cfg  HTTPConfig
+		err  bool
+	}{
+		{"defaults", HTTPConfig{}, false},
+		{"settings", HTTPConfig{Proxy: "http://proxy:3128", Timeout: "5s", Retries: &retries}, false},
+		{"bad timeout", HTTPConfig{Timeout: "soon"}, true},
+		{"bad proxy", HTTPConfig{Proxy: "proxy"}, true},
+		{"missing bundle", HTTPConfig{CABundle: filepath.Join(t.TempDir(), "ca.pem")}, true},
+	}
+
+	for _, test := range tests {
+		client, err := MakeHTTPClient(&Config{HTTP: &test.cfg})
+		if (err != nil) != test.err {
+			t.Errorf("%s: got error %v", test.name, err)
+		}
+		if test.cfg.Retries != nil && err == nil && client.Retries != *test.cfg.Retries {
+			t.Errorf("%s: got %d retries", test.name, client.Retries)
+		}
+	}
+}
+
+func TestUpdateCatalogRevalidates(t *testing.T) {
+	cat := makeTestCatalog("test", "Test", "v1.0.0")
+	etag := `"1"`
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/catalog.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fetches++
+		b, _ := yaml.Marshal(cat)
+		w.Header().Set("ETag", etag)
+		w.Write(b)
+	}))
+	defer server.Close()
+
+	cm := MakeCatalogManager(t.TempDir())
+	uri := server.URL + "/catalog.yaml"
+	if err := cm.AddCatalogFromUri(uri); err != nil {
+		t.Fatal(err)
+	}
+	if cm.State[uri].ETag != etag {
+		t.Errorf("got etag %q, want %q", cm.State[uri].ETag, etag)
+	}
+
+	refreshed := cm.State[uri].Refreshed
+	if _, err := cm.UpdateCatalog(uri); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 1 || !cm.State[uri].Refreshed.After(refreshed) {
+		t.Errorf("got %d fetches, want the unchanged catalog to be revalidated", fetches)
+	}
+
+	cat = makeTestCatalog("test", "Changed", "v1.0.0", "v2.0.0")
+	etag = `"2"`
+	if _, err := cm.UpdateCatalog(uri); err != nil {
+		t.Fatal(err)
+	}
+	if fd := cm.Functions["konveyor.io/RouteFunction"]; fd.Description != "Changed" || cm.State[uri].ETag != etag {
+		t.Errorf("got %s with etag %s, want the changed catalog", fd.Description, cm.State[uri].ETag)
+	}
+
+	var httpErr *HTTPError
+	_, err := cm.GetExternalCatalog(server.URL + "/missing.yaml")
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got error %v, want the status of the response", err)
+	}
+}
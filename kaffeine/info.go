@@ -0,0 +1,210 @@
+package kaffeine
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// Everything known about a single function, see GetFunctionInfo
+type FunctionInfo struct {
+	Definition FunctionDefinition `json:"definition"`
+	// URI of the catalog the function comes from, "" if it is in none
+	Catalog string `json:"catalog,omitempty"`
+	// The installed version and how it was requested, "" if not installed
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	RequestedVersion string `json:"requestedVersion,omitempty"`
+}
+
+// Returns the function with the given name as found in the catalogs, along
+// with its installed version. If a version or range is given, only matching
+// versions are included. Installed functions that are in no catalog anymore
+// are described by their installed definition.
+func (fm *FunctionManager) GetFunctionInfo(fname string) (info FunctionInfo, err error) {
+	alias, _ := SplitCatalogAlias(fname)
+	group, name, version := ToGroupNameVersion(fname)
+	groupName := group + "/" + name
+
+	functions, err := fm.CatMan.catalogFunctions(alias)
+	if err != nil {
+		return info, err
+	}
+
+	installed, isInstalled := fm.Installed[groupName]
+	if isInstalled {
+		info.InstalledVersion = installed.Versions[0].Name
+		info.RequestedVersion = installed.RequestedVersion()
+	}
+
+	fd, ok := functions[groupName]
+	if ok && alias != "" {
+		info.Catalog, _ = fm.CatMan.ResolveCatalogAlias(alias)
+	} else if ok {
+		info.Catalog, _ = fm.CatMan.FindCatalog(groupName)
+	} else if isInstalled {
+		fd = installed
+		restoreOriginalBinaryUri(&fd)
+	} else {
+		return info, fm.notFoundError(groupName)
+	}
+
+	if version != "" {
+		fds, err := fm.CatMan.Search(fname, false)
+		if err != nil {
+			return info, err
+		}
+		found := false
+		for _, match := range fds {
+			if match.GroupName() == groupName {
+				fd.Versions = match.Versions
+				found = true
+			}
+		}
+		if !found {
+			return info, fmt.Errorf("no version of function '%s' matches '%s'", groupName, version)
+		}
+	}
+
+	versions := make([]FunctionVersion, len(fd.Versions))
+	copy(versions, fd.Versions)
+	SortVersions(versions)
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+	fd.Versions = versions
+	info.Definition = fd
+
+	return info, nil
+}
+
+// Lists similarly named functions, to help with typos
+func (fm *FunctionManager) notFoundError(groupName string) error {
+	_, name, _ := ToGroupNameVersion(groupName)
+	fds, _ := fm.CatMan.Query(name)
+
+	similar := []string{}
+	for _, fd := range fds {
+		similar = append(similar, fd.GroupName())
+	}
+	if len(similar) == 0 {
+		return fmt.Errorf("function '%s' not found in any catalog", groupName)
+	}
+	if len(similar) > 5 {
+		similar = similar[:5]
+	}
+
+	return fmt.Errorf("function '%s' not found in any catalog (did you mean %s?)", groupName, strings.Join(similar, ", "))
+}
+
+// Returns a human-readable description of the function with the given name.
+// See GetFunctionInfo
+func (fm *FunctionManager) DescribeFunction(fname string) (result []byte, err error) {
+	info, err := fm.GetFunctionInfo(fname)
+	if err != nil {
+		return nil, err
+	}
+	return info.Describe(), nil
+}
+
+// Returns a human-readable description of the function
+func (info FunctionInfo) Describe() []byte {
+	var b bytes.Buffer
+	fd := info.Definition
+
+	fmt.Fprintln(&b, fd.GroupName())
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	field := func(indent string, name string, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s%s:\t%s\n", indent, name, value)
+		}
+	}
+
+	field("", "Description", fd.Description)
+	field("", "Publisher", fd.Publisher)
+	field("", "Home", fd.Home)
+	field("", "Maintainers", strings.Join(fd.Maintainers, ", "))
+	field("", "Tags", strings.Join(fd.Tags, ", "))
+	if info.Catalog != "" {
+		field("", "Catalog", info.Catalog)
+	} else {
+		field("", "Catalog", "(not in any catalog)")
+	}
+	switch {
+	case info.InstalledVersion == "":
+		field("", "Installed", "no")
+	case info.RequestedVersion != "":
+		field("", "Installed", info.InstalledVersion+" (requested "+info.RequestedVersion+")")
+	default:
+		field("", "Installed", info.InstalledVersion)
+	}
+	w.Flush()
+
+	fmt.Fprintln(&b, "\nVersions:")
+	for _, v := range fd.Versions {
+		name := v.Name
+		if info.InstalledVersion != "" && v.Name == info.InstalledVersion {
+			name += " (installed)"
+		}
+		fmt.Fprintln(&b, "  "+name)
+
+		w = tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+		field("    ", "License", v.License)
+		field("    ", "Idempotent", fmt.Sprint(v.Idempotent))
+		field("    ", "Usage", v.Usage)
+		field("    ", "Maintainers", strings.Join(v.Maintainers, ", "))
+		for i, example := range v.Examples {
+			if i == 0 {
+				field("    ", "Examples", example)
+			} else {
+				fmt.Fprintf(w, "    \t%s\n", example)
+			}
+		}
+		if c := v.Runtime.Container; c.Image != "" {
+			field("    ", "Container", describeContainer(c))
+		}
+		for i, p := range v.Runtime.Exec.Platforms {
+			name := ""
+			if i == 0 {
+				name = "Exec:"
+			}
+			fmt.Fprintf(w, "    %s\t%s\n", name, describePlatform(p))
+		}
+		w.Flush()
+	}
+
+	return b.Bytes()
+}
+
+func describeContainer(c FunctionRuntimeContainer) string {
+	s := c.Image
+	details := []string{}
+	if c.Sha256 != "" {
+		details = append(details, "sha256 "+c.Sha256)
+	}
+	if c.RequireNetwork {
+		details = append(details, "requires network")
+	}
+	if c.RequireStorageMount {
+		details = append(details, "requires storage mount")
+	}
+	if len(details) > 0 {
+		s += " (" + strings.Join(details, ", ") + ")"
+	}
+	return s
+}
+
+func describePlatform(p FunctionRuntimePlatform) string {
+	s := p.Os + "/" + p.Arch + "  " + p.Uri
+	details := []string{}
+	if p.Bin != "" {
+		details = append(details, "bin "+p.Bin)
+	}
+	if p.Sha256 != "" {
+		details = append(details, "sha256 "+p.Sha256)
+	}
+	if len(details) > 0 {
+		s += " (" + strings.Join(details, ", ") + ")"
+	}
+	return s
+}
@@ -0,0 +1,63 @@
+package kaffeine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGetFunctionInfo(t *testing.T) {
+	fm := makeTestFunctionManager(t, "v1.0.0", "v1.2.0", "v2.0.0")
+	fd, err := fm.GetExternalFunctionDefinition("example.com/Logger@^1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm.Installed[fd.GroupName()] = fd
+
+	var tests = []struct {
+		fname    string
+		versions string
+		wantErr  string
+	}{
+		{"example.com/Logger", "[v2.0.0 v1.2.0 v1.0.0]", ""},
+		{"example.com/Logger@^1.0", "[v1.2.0 v1.0.0]", ""},
+		{"example.com/Logger@v3", "", "no version"},
+		{"example.com/Loger", "", "not found in any catalog"},
+		{"example.org/Logger", "", "did you mean example.com/Logger?"},
+	}
+
+	for _, test := range tests {
+		info, err := fm.GetFunctionInfo(test.fname)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: got error %v, want error containing %q", test.fname, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.fname, err)
+			continue
+		}
+
+		versions := []string{}
+		for _, v := range info.Definition.Versions {
+			versions = append(versions, v.Name)
+		}
+		if fmt.Sprint(versions) != test.versions {
+			t.Errorf("%s: got versions %v, want %s", test.fname, versions, test.versions)
+		}
+		if info.Catalog != "file:///test.yaml" || info.InstalledVersion != "v1.2.0" || info.RequestedVersion != "^1.0" {
+			t.Errorf("%s: got catalog %q, installed %q, requested %q", test.fname, info.Catalog, info.InstalledVersion, info.RequestedVersion)
+		}
+	}
+
+	description, err := fm.DescribeFunction("example.com/Logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"v1.2.0 (requested ^1.0)\n", "  v1.2.0 (installed)\n"} {
+		if !strings.Contains(string(description), want) {
+			t.Errorf("description %q does not contain %q", description, want)
+		}
+	}
+}
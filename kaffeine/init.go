@@ -0,0 +1,58 @@
+package kaffeine
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Returned by GetDirectory when there is no ".kaffeine" directory in the
+// working directory or any of its parents
+var ErrNoDirectory = errors.New("no .kaffeine directory found (run 'kaffeine init' to create one)")
+
+//go:embed default_config.yaml
+var defaultConfig string
+
+var defaultConfigTemplate = template.Must(template.New("config").Parse(defaultConfig))
+
+// Creates the ".kaffeine" directory at directory with a config.yaml made from
+// default_config.yaml, listing the given starter catalogs. Fails if the
+// directory already has a config.
+func InitDirectory(directory string, catalogs []string) error {
+	filePath := filepath.Join(directory, "config.yaml")
+	if _, err := os.Stat(filePath); err == nil {
+		return fmt.Errorf("'%s' is already initialized", directory)
+	}
+
+	starters := []CatalogConfig{}
+	for _, uri := range catalogs {
+		starters = append(starters, CatalogConfig{Uri: uri})
+	}
+	nameCatalogs(starters)
+
+	var b bytes.Buffer
+	err := defaultConfigTemplate.Execute(&b, struct{ Catalogs []CatalogConfig }{starters})
+	if err != nil {
+		return err
+	}
+
+	// Make sure the catalogs survived being templated into yaml
+	c := Config{}
+	if err := yaml.Unmarshal(b.Bytes(), &c); err != nil {
+		return fmt.Errorf("invalid starter config: %w", err)
+	}
+	if fmt.Sprint(c.CatalogUris()) != fmt.Sprint(catalogs) && len(catalogs) > 0 {
+		return fmt.Errorf("invalid starter catalogs %q", catalogs)
+	}
+
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, b.Bytes(), 0644)
+}
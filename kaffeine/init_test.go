@@ -0,0 +1,77 @@
+package kaffeine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitDirectory(t *testing.T) {
+	var tests = []struct {
+		name     string
+		catalogs []string
+	}{
+		{"no catalogs", nil},
+		{"catalogs", []string{"https://example.com/catalog.yaml", "file:///tmp/my catalog #1.yaml"}},
+	}
+
+	t.Setenv("KAFFEINE_GLOBAL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+
+	for _, test := range tests {
+		dir := filepath.Join(t.TempDir(), ".kaffeine")
+		if err := InitDirectory(dir, test.catalogs); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		c := MakeConfig(dir)
+		if fmt.Sprint(c.CatalogUris()) != fmt.Sprint(test.catalogs) {
+			t.Errorf("%s: config has catalogs %v, want %v", test.name, c.CatalogUris(), test.catalogs)
+		}
+
+		if err := InitDirectory(dir, test.catalogs); err == nil {
+			t.Errorf("%s: expected error initializing twice", test.name)
+		}
+	}
+}
+
+func TestGetDirectory(t *testing.T) {
+	project := t.TempDir()
+	t.Setenv("KAFFEINE_GLOBAL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	nested := filepath.Join(project, "a", "b")
+	if err := os.MkdirAll(nested, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(nested); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GetDirectory(); !errors.Is(err, ErrNoDirectory) {
+		t.Errorf("got error %v, want ErrNoDirectory", err)
+	}
+	if _, err := NewReadOnlyFunctionManager(""); !errors.Is(err, ErrNoDirectory) {
+		t.Errorf("got error %v, want ErrNoDirectory", err)
+	}
+	if _, err := os.Stat(filepath.Join(nested, ".kaffeine")); !os.IsNotExist(err) {
+		t.Errorf("looking for the directory created it")
+	}
+
+	if err := InitDirectory(filepath.Join(project, ".kaffeine"), nil); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := GetDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := filepath.EvalSymlinks(filepath.Join(project, ".kaffeine")); dir != want && dir != filepath.Join(project, ".kaffeine") {
+		t.Errorf("got directory %s, want %s", dir, want)
+	}
+}
@@ -2,7 +2,7 @@ package kaffeine
 
 import (
 	"fmt"
-	"sort"
+	"strings"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -31,6 +31,10 @@ func MakeFunctionCatalog(name string) (fc FunctionCatalog) {
 var IgnoreAutoUpdates string = "kaffeine.config/ignore-auto-updates"
 var OriginalBinaryLocation string = "kaffeine.config/original-binary-location"
 var LocalBinaryLocation string = "kaffeine.config/local-binary-location"
+var VersionConstraint string = "kaffeine.config/version-constraint"
+var BinarySha256 string = "kaffeine.config/binary-sha256"
+var Platform string = "kaffeine.config/platform"
+var CatalogAlias string = "kaffeine.config/catalog"
 
 type FunctionDefinition struct {
 	// required
@@ -48,27 +52,92 @@ type FunctionDefinition struct {
 	Metadata    *v1.ObjectMeta `json:"metadata,omitempty"`
 }
 
-// Right now just lexicographically compares the version names
-func (m FunctionDefinition) GetHighestVersion() FunctionVersion {
-	sort.Slice(m.Versions, func(i, j int) bool {
-		return m.Versions[i].Name < m.Versions[j].Name
-	})
+// Returns the highest version of the function, as ordered by
+// CompareVersionNames. Pre-releases are skipped unless includePrerelease is
+// set or the function has nothing but pre-releases.
+func (m FunctionDefinition) GetHighestVersion(includePrerelease bool) FunctionVersion {
+	versions := make([]FunctionVersion, len(m.Versions))
+	copy(versions, m.Versions)
+	SortVersions(versions)
+
+	if !includePrerelease {
+		for i := len(versions) - 1; i >= 0; i-- {
+			if !IsPrereleaseName(versions[i].Name) {
+				return versions[i]
+			}
+		}
+	}
+
+	return versions[len(versions)-1]
+}
+
+// Returns the highest version of the function that satisfies the constraint
+func (m FunctionDefinition) GetHighestMatchingVersion(c Constraint, includePrerelease bool) (fv FunctionVersion, err error) {
+	versions := make([]FunctionVersion, len(m.Versions))
+	copy(versions, m.Versions)
+	SortVersions(versions)
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		if c.Check(versions[i].Name, includePrerelease) {
+			return versions[i], nil
+		}
+	}
 
-	return m.Versions[len(m.Versions)-1]
+	return fv, fmt.Errorf("no version of function '%s' satisfies '%s'", m.GroupName(), c)
 }
 
+// Returns the version with the given name. See VersionNamesEqual
 func (m FunctionDefinition) GetVersion(v string) (fv FunctionVersion, err error) {
 	for _, fv = range m.Versions {
 		if fv.Name == v {
 			return fv, nil
 		}
 	}
+	for _, fv = range m.Versions {
+		if VersionNamesEqual(fv.Name, v) {
+			return fv, nil
+		}
+	}
 
-	return fv, fmt.Errorf("no version '%s' in function '%s'", m.GroupName(), v)
+	return fv, fmt.Errorf("no version '%s' in function '%s'", v, m.GroupName())
+}
+
+// Returns how the version of the installed function was requested: a version
+// range, a pegged version, or "" if it follows the latest version
+func (m FunctionDefinition) RequestedVersion() string {
+	if m.Metadata == nil {
+		return ""
+	}
+	if val := m.Metadata.Annotations[VersionConstraint]; val != "" {
+		return val
+	}
+	if m.Metadata.Annotations[IgnoreAutoUpdates] == "true" {
+		return m.Versions[0].Name
+	}
+	return ""
+}
+
+// Returns the alias of the catalog the function was requested from, as in
+// "alias::group/name", or "" if it may come from any catalog
+func (m FunctionDefinition) CatalogAlias() string {
+	if m.Metadata == nil {
+		return ""
+	}
+	return m.Metadata.Annotations[CatalogAlias]
+}
+
+// Splits "alias::group/name@version" into the catalog alias and the rest. The
+// alias is "" if there is none.
+func SplitCatalogAlias(fname string) (alias string, rest string) {
+	if i := strings.LastIndex(fname, "::"); i >= 0 {
+		return fname[:i], fname[i+2:]
+	}
+	return "", fname
 }
 
-// Get rightmost @ and get rightmost /
+// Get rightmost @ and get rightmost /, ignoring the catalog alias
 func ToGroupNameVersion(nameString string) (group string, name string, version string) {
+	_, nameString = SplitCatalogAlias(nameString)
 	for i := len(nameString) - 1; i >= 0; i-- {
 		if nameString[i:i+1] == "@" {
 			version = nameString[i+1:]
@@ -24,3 +24,33 @@ func TestToGroupNameVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestGetHighestVersion(t *testing.T) {
+	var tests = []struct {
+		versions   []string
+		prerelease bool
+		want       string
+	}{
+		{[]string{"v9.0.0", "v10.0.0"}, false, "v10.0.0"},
+		{[]string{"v1.0.2", "v1.0.10", "v1.0.1"}, false, "v1.0.10"},
+		{[]string{"v1.0.0", "v2.0.0-rc.1"}, false, "v1.0.0"},
+		{[]string{"v1.0.0", "v2.0.0-rc.1"}, true, "v2.0.0-rc.1"},
+		{[]string{"v2.0.0-rc.1", "v2.0.0-rc.2"}, false, "v2.0.0-rc.2"},
+		{[]string{"latest", "v3"}, false, "v3"},
+		{[]string{"v3"}, false, "v3"},
+	}
+
+	for _, test := range tests {
+		fd := FunctionDefinition{}
+		for _, v := range test.versions {
+			fd.Versions = append(fd.Versions, FunctionVersion{Name: v})
+		}
+
+		if got := fd.GetHighestVersion(test.prerelease).Name; got != test.want {
+			t.Errorf("%v (prerelease %v): got %s, want %s", test.versions, test.prerelease, got, test.want)
+		}
+		if fd.Versions[0].Name != test.versions[0] {
+			t.Errorf("%v: GetHighestVersion reordered the versions", test.versions)
+		}
+	}
+}
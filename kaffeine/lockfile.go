@@ -0,0 +1,294 @@
+package kaffeine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Lockfile struct. Unmarshalled from ".kaffeine/kaffeine.lock", it records
+// exactly what every installed function was resolved to so that installs can
+// be reproduced.
+type Lockfile struct {
+	// The filepath, usually ".kaffeine/kaffeine.lock"
+	FilePath string `json:"-"`
+
+	LockfileVersion int              `json:"lockfileVersion"`
+	Functions       []LockedFunction `json:"functions"`
+}
+
+type LockedFunction struct {
+	// The GroupName of the function
+	Name string `json:"name"`
+	// The version the function was resolved to
+	Version string `json:"version"`
+	// The version or range requested in the config, if any
+	Requested string `json:"requested,omitempty"`
+	// The catalog the function is pinned to in the config, if any, see
+	// SplitCatalogAlias
+	CatalogAlias string `json:"catalogAlias,omitempty"`
+
+	// The uri and content hash of the catalog the function was resolved from
+	Catalog       string `json:"catalog,omitempty"`
+	CatalogSha256 string `json:"catalogSha256,omitempty"`
+	// The commit git catalogs are pinned to, see CatalogState.Commit
+	CatalogCommit string `json:"catalogCommit,omitempty"`
+
+	Image       string         `json:"image,omitempty"`
+	ImageSha256 string         `json:"imageSha256,omitempty"`
+	Binaries    []LockedBinary `json:"binaries,omitempty"`
+}
+
+type LockedBinary struct {
+	Os     string `json:"os"`
+	Arch   string `json:"arch"`
+	Uri    string `json:"uri"`
+	Sha256 string `json:"sha256"`
+}
+
+// Reads the lockfile in the given directory. Returns an error wrapping
+// os.ErrNotExist if there is none.
+func ReadLockfile(directory string) (l Lockfile, err error) {
+	l.FilePath = filepath.Join(directory, "kaffeine.lock")
+
+	data, err := os.ReadFile(l.FilePath)
+	if err != nil {
+		return l, fmt.Errorf("could not read lockfile: %w", err)
+	}
+
+	err = yaml.Unmarshal(data, &l)
+	if err != nil {
+		return l, fmt.Errorf("could not parse lockfile '%s': %v", l.FilePath, err)
+	}
+
+	return l, nil
+}
+
+// Saves lockfile struct
+func (l *Lockfile) Save() error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(l.FilePath, data, 0644)
+}
+
+// Returns the lockfile describing the currently installed functions
+func (fm *FunctionManager) GenerateLockfile() (l Lockfile, err error) {
+	l.FilePath = filepath.Join(fm.Directory, "kaffeine.lock")
+	l.LockfileVersion = 1
+	l.Functions = []LockedFunction{}
+
+	for _, fd := range fm.Installed {
+		lf, err := fm.lockFunction(fd)
+		if err != nil {
+			return l, err
+		}
+		l.Functions = append(l.Functions, lf)
+	}
+
+	// Functions that could not be resolved keep what was locked for them
+	if len(fm.Unresolved) > 0 {
+		if old, err := ReadLockfile(fm.Directory); err == nil {
+			for _, lf := range old.Functions {
+				if _, ok := fm.Unresolved[lf.Name]; ok {
+					l.Functions = append(l.Functions, lf)
+				}
+			}
+		}
+	}
+
+	sort.Slice(l.Functions, func(i, j int) bool {
+		return l.Functions[i].Name < l.Functions[j].Name
+	})
+
+	return l, nil
+}
+
+func (fm *FunctionManager) lockFunction(fd FunctionDefinition) (lf LockedFunction, err error) {
+	lf.Name = fd.GroupName()
+	lf.Version = fd.Versions[0].Name
+	lf.Requested = fd.RequestedVersion()
+	lf.CatalogAlias = fd.CatalogAlias()
+
+	uri, ok := fm.CatMan.FindVersionCatalog(lf.Name, lf.Version)
+	if alias := fd.CatalogAlias(); alias != "" {
+		var aliasErr error
+		uri, aliasErr = fm.CatMan.ResolveCatalogAlias(alias)
+		ok = aliasErr == nil
+	}
+	if ok {
+		lf.Catalog = uri
+		lf.CatalogSha256, err = fm.CatMan.Hash(uri)
+		if err != nil {
+			return
+		}
+		lf.CatalogCommit = fm.CatMan.State[uri].Commit
+	}
+
+	runtime := fd.Versions[0].Runtime
+	lf.Image = runtime.Container.Image
+	lf.ImageSha256 = runtime.Container.Sha256
+	// The binary that was downloaded is locked with its original uri and
+	// actual digest
+	installed := fd.installedPlatformIndex()
+	for i, p := range runtime.Exec.Platforms {
+		uri, sha := p.Uri, p.Sha256
+		if i == installed {
+			if val := fd.Metadata.Annotations[OriginalBinaryLocation]; val != "" {
+				uri = val
+			}
+			if val := fd.Metadata.Annotations[BinarySha256]; val != "" {
+				sha = val
+			}
+		}
+		lf.Binaries = append(lf.Binaries, LockedBinary{Os: p.Os, Arch: p.Arch, Uri: uri, Sha256: sha})
+	}
+
+	return
+}
+
+// Replaces the installed functions with exactly the ones recorded in the
+// lockfile. Returns an error if the config, the catalogs or the functions they
+// contain deviate from the lockfile in any way. Once frozen, Save leaves the
+// config alone and refuses to write a lockfile that differs from the one on
+// disk.
+func (fm *FunctionManager) InstallFrozen() error {
+	lock, err := ReadLockfile(fm.Directory)
+	if err != nil {
+		return err
+	}
+
+	locked := map[string]LockedFunction{}
+	for _, lf := range lock.Functions {
+		locked[lf.Name] = lf
+	}
+
+	requested := map[string]string{}
+	aliases := map[string]string{}
+	for _, fname := range fm.Cfg.Dependencies.KrmFunctions {
+		group, name, version := ToGroupNameVersion(fname)
+		requested[group+"/"+name] = version
+		aliases[group+"/"+name], _ = SplitCatalogAlias(fname)
+	}
+
+	for _, lf := range lock.Functions {
+		if _, ok := requested[lf.Name]; !ok {
+			return fmt.Errorf("lockfile contains '%s', which is not a dependency in the config", lf.Name)
+		}
+	}
+
+	installed := map[string]FunctionDefinition{}
+	for groupName, version := range requested {
+		lf, ok := locked[groupName]
+		if !ok {
+			return fmt.Errorf("dependency '%s' is not in the lockfile", groupName)
+		}
+		if lf.Requested != version {
+			return fmt.Errorf("config requests '%s@%s', but the lockfile was written for '%s@%s'", groupName, version, groupName, lf.Requested)
+		}
+		if lf.CatalogAlias != aliases[groupName] {
+			return fmt.Errorf("config pins '%s' to catalog '%s', but the lockfile was written for catalog '%s'", groupName, aliases[groupName], lf.CatalogAlias)
+		}
+
+		fd, err := fm.resolveLocked(lf)
+		if err != nil {
+			return err
+		}
+		installed[groupName] = fd
+	}
+
+	fm.Installed = installed
+	fm.Frozen = true
+
+	return nil
+}
+
+// Finds the function described by the locked function in its catalog
+func (fm *FunctionManager) resolveLocked(lf LockedFunction) (fd FunctionDefinition, err error) {
+	cat, ok := fm.CatMan.Catalogs[lf.Catalog]
+	if !ok {
+		return fd, fmt.Errorf("catalog '%s' of locked function '%s' is not present", lf.Catalog, lf.Name)
+	}
+
+	hash, err := fm.CatMan.Hash(lf.Catalog)
+	if err != nil {
+		return
+	}
+	if hash != lf.CatalogSha256 {
+		return fd, fmt.Errorf("catalog '%s' changed since the lockfile was written (sha256 %s, locked %s)", lf.Catalog, hash, lf.CatalogSha256)
+	}
+
+	found := false
+	for _, fn := range cat.Spec.KrmFunctions {
+		if fn.GroupName() == lf.Name {
+			fd, found = fn, true
+			break
+		}
+	}
+	if !found {
+		return fd, fmt.Errorf("locked function '%s' not found in catalog '%s'", lf.Name, lf.Catalog)
+	}
+
+	v, err := fd.GetVersion(lf.Version)
+	if err != nil {
+		return
+	}
+	v.Runtime.Exec.Platforms = lockedPlatforms(v.Runtime.Exec.Platforms, lf.Binaries)
+	setRequestedVersion(&fd, lf.Requested)
+	setCatalogAlias(&fd, lf.CatalogAlias)
+	fd.Versions = []FunctionVersion{v}
+
+	resolved, err := fm.lockFunction(fd)
+	if err != nil {
+		return
+	}
+	if !reflect.DeepEqual(resolved, lf) {
+		return fd, fmt.Errorf("function '%s' resolves differently than recorded in the lockfile", lf.Name)
+	}
+
+	return fd, nil
+}
+
+// Returns a copy of the platforms with the sha256 of their locked binaries,
+// which is what was downloaded when the lockfile was written
+func lockedPlatforms(platforms []FunctionRuntimePlatform, binaries []LockedBinary) []FunctionRuntimePlatform {
+	result := make([]FunctionRuntimePlatform, len(platforms))
+	copy(result, platforms)
+	for i, p := range result {
+		for _, b := range binaries {
+			if b.Os == p.Os && b.Arch == p.Arch && b.Uri == p.Uri {
+				result[i].Sha256 = b.Sha256
+			}
+		}
+	}
+	return result
+}
+
+// Writes the lockfile for the installed functions. When frozen, the lockfile
+// is left untouched and an error is returned if it would change.
+func (fm *FunctionManager) saveLockfile() error {
+	lock, err := fm.GenerateLockfile()
+	if err != nil {
+		return err
+	}
+
+	if fm.Frozen {
+		old, err := ReadLockfile(fm.Directory)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if !reflect.DeepEqual(old, lock) {
+			return errors.New("lockfile is frozen, refusing to change it")
+		}
+		return nil
+	}
+
+	return lock.Save()
+}
@@ -0,0 +1,175 @@
+package kaffeine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallFrozen(t *testing.T) {
+	fm := makeTestFunctionManager(t, "v1.0.0", "v1.1.0")
+	fm.Directory = t.TempDir()
+	if _, err := fm.AddFunctionDefinition("example.com/Logger@^1.0"); err != nil {
+		t.Fatal(err)
+	}
+	fm.UpdateConfig()
+
+	lock, err := fm.GenerateLockfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.Functions) != 1 {
+		t.Fatalf("expected 1 locked function, got %d", len(lock.Functions))
+	}
+	lf := lock.Functions[0]
+	if lf.Name != "example.com/Logger" || lf.Version != "v1.1.0" || lf.Requested != "^1.0" || lf.Catalog != "file:///test.yaml" || lf.CatalogSha256 == "" {
+		t.Errorf("unexpected locked function %+v", lf)
+	}
+	if err := lock.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged catalogs install exactly what was locked
+	if err := fm.InstallFrozen(); err != nil {
+		t.Fatal(err)
+	}
+	if v := fm.Installed["example.com/Logger"].Versions[0].Name; v != "v1.1.0" {
+		t.Errorf("frozen install resolved %s, want v1.1.0", v)
+	}
+	if err := fm.saveLockfile(); err != nil {
+		t.Errorf("saving an unchanged frozen lockfile: %v", err)
+	}
+
+	// Config no longer matches the lockfile
+	fm.Cfg.Dependencies.KrmFunctions = []string{"example.com/Logger@^2.0"}
+	if err := fm.InstallFrozen(); err == nil {
+		t.Errorf("expected error when config deviates from lockfile")
+	}
+	fm.Cfg.Dependencies.KrmFunctions = []string{"example.com/Logger@^1.0", "example.com/Other"}
+	if err := fm.InstallFrozen(); err == nil {
+		t.Errorf("expected error when dependency is missing from lockfile")
+	}
+	fm.Cfg.Dependencies.KrmFunctions = []string{"example.com/Logger@^1.0"}
+
+	// A new version is published in the catalog
+	cat := fm.CatMan.Catalogs["file:///test.yaml"]
+	cat.Spec.KrmFunctions[0].Versions = append(cat.Spec.KrmFunctions[0].Versions, FunctionVersion{Name: "v1.2.0"})
+	fm.CatMan.Catalogs["file:///test.yaml"] = cat
+	if err := fm.InstallFrozen(); err == nil {
+		t.Errorf("expected error when catalog deviates from lockfile")
+	}
+}
+
+func TestInstallFrozenCatalogAlias(t *testing.T) {
+	fm := makeTestMirrors(t)
+	fm.Directory = t.TempDir()
+	fm.Cfg.FilePath = filepath.Join(fm.Directory, "config.yaml")
+	if _, err := fm.AddFunctionDefinition("mirror::konveyor.io/RouteFunction@v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fm.Save(); err != nil {
+		t.Fatal(err)
+	}
+	config, _ := os.ReadFile(fm.Cfg.FilePath)
+
+	lock, err := ReadLockfile(fm.Directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lf := lock.Functions[0]; lf.CatalogAlias != "mirror" || lf.Catalog != "file:///mirror.yaml" {
+		t.Errorf("got alias %q for catalog %s, want the mirror", lf.CatalogAlias, lf.Catalog)
+	}
+
+	// The version is in both catalogs, only the alias selects the mirror
+	if err := fm.InstallFrozen(); err != nil {
+		t.Fatal(err)
+	}
+	if alias := fm.Installed["konveyor.io/RouteFunction"].CatalogAlias(); alias != "mirror" {
+		t.Errorf("got alias %q after frozen install, want mirror", alias)
+	}
+	// Nothing in memory is written back to the config once frozen
+	fm.Cfg.Dependencies.KrmFunctions = append(fm.Cfg.Dependencies.KrmFunctions, "unused")
+	if err := fm.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := os.ReadFile(fm.Cfg.FilePath); string(saved) != string(config) {
+		t.Errorf("frozen install rewrote the config:\n%s", saved)
+	}
+
+	fm.Cfg.Dependencies.KrmFunctions = []string{"upstream::konveyor.io/RouteFunction@v1.0.0"}
+	if err := fm.InstallFrozen(); err == nil {
+		t.Errorf("expected error when the config pins another catalog")
+	}
+}
+
+func TestLockVerifiedDigest(t *testing.T) {
+	content := []byte("#!/bin/sh\necho\n")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer srv.Close()
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	cat := MakeFunctionCatalog("test")
+	fd := FunctionDefinition{Group: "example.com", Versions: []FunctionVersion{{Name: "v1.0.0"}}}
+	fd.Names.Kind = "Echo"
+	fd.Versions[0].Runtime.Exec.Platforms = []FunctionRuntimePlatform{
+		{Os: "linux", Arch: "amd64", Bin: "echo", Uri: srv.URL + "/echo", Sha256: "deadbeef"},
+		{Os: "darwin", Arch: "arm64", Bin: "echo", Uri: srv.URL + "/echo-darwin", Sha256: "deadbeef"},
+	}
+	cat.Spec.KrmFunctions = append(cat.Spec.KrmFunctions, fd)
+
+	dir := t.TempDir()
+	newFunctionManager := func() *FunctionManager {
+		catman := MakeCatalogManager(t.TempDir())
+		if err := catman.AddCatalogFromStruct("file:///test.yaml", cat); err != nil {
+			t.Fatal(err)
+		}
+		cfg := &Config{FilePath: filepath.Join(dir, "config.yaml")}
+		cfg.Dependencies.KrmFunctions = []string{"example.com/Echo"}
+		os.MkdirAll(filepath.Join(dir, "functions"), os.ModePerm)
+		return &FunctionManager{Directory: dir, CatMan: &catman, Cfg: cfg, Store: MakeBinaryStore(t.TempDir()), Installed: map[string]FunctionDefinition{}, Platform: "linux/amd64"}
+	}
+
+	// The placeholder is not locked, but what was actually downloaded
+	fm := newFunctionManager()
+	fm.SkipDigestVerification = true
+	if _, err := fm.AddFunctionDefinition("example.com/Echo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fm.Save(); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := ReadLockfile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lock.Functions[0].Binaries; got[0].Sha256 != digest || got[1].Sha256 != "deadbeef" {
+		t.Errorf("got binaries %+v, want the downloaded one locked with %s", got, digest)
+	}
+
+	// A frozen install accepts the same binary, but not a changed one
+	os.RemoveAll(filepath.Join(dir, "functions"))
+	fm = newFunctionManager()
+	if err := fm.InstallFrozen(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fm.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	os.RemoveAll(filepath.Join(dir, "functions"))
+	content = []byte("changed")
+	fm = newFunctionManager()
+	if err := fm.InstallFrozen(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fm.Save(); err == nil {
+		t.Errorf("expected error when the binary deviates from the lockfile")
+	}
+}
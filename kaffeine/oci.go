@@ -0,0 +1,300 @@
+package kaffeine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Media types of the manifests that are accepted from registries
+var ociManifestTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+const ociIndexType = "application/vnd.oci.image.index.v1+json"
+
+// Annotation holding the file name of a layer, as set by oras
+const ociTitleAnnotation = "org.opencontainers.image.title"
+
+// A catalog or binary stored as an OCI artifact, written as
+// "oci://registry/repository:tag" or "oci://registry/repository@sha256:...".
+// Artifacts with several layers select one by its title with a fragment, as
+// in "oci://registry/repository:tag#catalog.yaml".
+type OCIReference struct {
+	Registry   string
+	Repository string
+	// A tag or a digest
+	Reference string
+	Layer     string
+}
+
+func ParseOCIReference(uri string) (ref OCIReference, err error) {
+	if !strings.HasPrefix(uri, "oci://") {
+		return ref, fmt.Errorf("'%s' is not an oci:// uri", uri)
+	}
+
+	rest, layer, _ := strings.Cut(strings.TrimPrefix(uri, "oci://"), "#")
+	ref.Layer = layer
+	var ok bool
+	ref.Registry, rest, ok = strings.Cut(rest, "/")
+	if !ok || ref.Registry == "" || rest == "" {
+		return ref, fmt.Errorf("oci uri '%s' has no repository", uri)
+	}
+
+	if repo, digest, ok := strings.Cut(rest, "@"); ok {
+		ref.Repository, ref.Reference = repo, digest
+		if !strings.HasPrefix(digest, "sha256:") || !IsValidSha256(digest[len("sha256:"):]) {
+			return ref, fmt.Errorf("invalid digest '%s' in oci uri '%s'", digest, uri)
+		}
+	} else if i := strings.LastIndex(rest, ":"); i >= 0 {
+		ref.Repository, ref.Reference = rest[:i], rest[i+1:]
+	} else {
+		ref.Repository, ref.Reference = rest, "latest"
+	}
+
+	if ref.Repository == "" || ref.Reference == "" || ref.Repository != strings.ToLower(ref.Repository) {
+		return ref, fmt.Errorf("invalid repository or reference in oci uri '%s'", uri)
+	}
+	return ref, nil
+}
+
+func (ref OCIReference) String() string {
+	sep := ":"
+	if strings.HasPrefix(ref.Reference, "sha256:") {
+		sep = "@"
+	}
+	s := "oci://" + ref.Registry + "/" + ref.Repository + sep + ref.Reference
+	if ref.Layer != "" {
+		s += "#" + ref.Layer
+	}
+	return s
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// Requests to a single repository of a registry, sharing the token obtained
+// from its token service
+type ociSession struct {
+	client *HTTPClient
+	ref    OCIReference
+	auth   string
+	token  string
+}
+
+// Opens the layer of the artifact at the oci uri and returns the digest of its
+// manifest. If the digest is knownDigest, the layer is not fetched and the
+// returned body is nil. The layer is checked against its digest while it is
+// read.
+func (c *HTTPClient) OpenOCI(uri string, auth string, knownDigest string) (body io.ReadCloser, digest string, err error) {
+	ref, err := ParseOCIReference(uri)
+	if err != nil {
+		return
+	}
+	if c == nil {
+		c = &HTTPClient{}
+	}
+	s := &ociSession{client: c, ref: ref, auth: auth}
+
+	data, err := s.get("manifests/"+ref.Reference, append([]string{ociIndexType}, ociManifestTypes...))
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+	digest = "sha256:" + hex.EncodeToString(sum[:])
+	if digest == knownDigest {
+		return nil, digest, nil
+	}
+
+	var manifest ociManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, "", fmt.Errorf("invalid manifest of '%s': %w", uri, err)
+	}
+	if manifest.MediaType == ociIndexType {
+		return nil, "", fmt.Errorf("'%s' is an image index, refer to the manifest of a single platform instead", uri)
+	}
+
+	layer, err := manifest.layer(ref)
+	if err != nil {
+		return
+	}
+
+	resp, err := s.open("blobs/"+layer.Digest, nil)
+	if err != nil {
+		return
+	}
+	return &digestReader{ReadCloser: resp.Body, uri: uri, expected: layer.Digest, h: sha256.New()}, digest, nil
+}
+
+// Returns the layer selected by the reference
+func (m ociManifest) layer(ref OCIReference) (layer ociDescriptor, err error) {
+	if ref.Layer == "" {
+		if len(m.Layers) != 1 {
+			return layer, fmt.Errorf("'%s' has %d layers, select one with '#<title>'", ref, len(m.Layers))
+		}
+		layer = m.Layers[0]
+	} else {
+		found := false
+		for _, l := range m.Layers {
+			if l.Annotations[ociTitleAnnotation] == ref.Layer {
+				layer, found = l, true
+				break
+			}
+		}
+		if !found {
+			return layer, fmt.Errorf("'%s' has no layer titled '%s'", ref, ref.Layer)
+		}
+	}
+
+	if !strings.HasPrefix(layer.Digest, "sha256:") {
+		return layer, fmt.Errorf("unsupported digest '%s' in '%s'", layer.Digest, ref)
+	}
+	return layer, nil
+}
+
+// Reads the response to the registry API path completely
+func (s *ociSession) get(path string, accept []string) ([]byte, error) {
+	resp, err := s.open(path, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Requests the registry API path of the repository. When the registry asks
+// for a bearer token, one is requested from its token service with the
+// credentials of the session, and the request is sent again with it.
+func (s *ociSession) open(path string, accept []string) (*http.Response, error) {
+	uri := "https://" + s.ref.Registry + "/v2/" + s.ref.Repository + "/" + path
+
+	for {
+		header := http.Header{}
+		if len(accept) > 0 {
+			header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if s.token != "" {
+			header.Set("Authorization", "Bearer "+s.token)
+		}
+
+		resp, err := s.client.Get(uri, s.auth, header)
+		var httpErr *HTTPError
+		if s.token != "" || !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+
+		scheme, params := parseChallenge(httpErr.Header.Get("WWW-Authenticate"))
+		if !strings.EqualFold(scheme, "bearer") || params["realm"] == "" {
+			return nil, err
+		}
+		if s.token, err = s.fetchToken(params); err != nil {
+			return nil, fmt.Errorf("could not authenticate to '%s': %w", s.ref.Registry, err)
+		}
+	}
+}
+
+// Requests a token from the token service of a bearer challenge
+func (s *ociSession) fetchToken(params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", err
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	if query.Get("scope") == "" {
+		query.Set("scope", "repository:"+s.ref.Repository+":pull")
+	}
+	realm.RawQuery = query.Encode()
+
+	// Token services take the credentials of the registry, by its host
+	auth := s.auth
+	if auth == "" {
+		auth = s.ref.Registry
+	}
+	if _, ok := s.client.credential(auth, realm); !ok {
+		auth = ""
+	}
+
+	resp, err := s.client.Get(realm.String(), auth, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	if body.Token == "" {
+		return "", errors.New("no token in response")
+	}
+	return body.Token, nil
+}
+
+// Splits a WWW-Authenticate header like `Bearer realm="...",service="..."`
+// into its scheme and parameters
+func parseChallenge(header string) (scheme string, params map[string]string) {
+	params = map[string]string{}
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.TrimSpace(key); key != "" {
+			params[strings.ToLower(key)] = value
+		}
+	}
+
+	return scheme, params
+}
+
+// Checks the content against its digest once it is read to the end
+type digestReader struct {
+	io.ReadCloser
+	uri      string
+	expected string
+	h        hash.Hash
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.h.Write(p[:n])
+	if err == io.EOF {
+		if actual := "sha256:" + hex.EncodeToString(r.h.Sum(nil)); actual != r.expected {
+			return n, &DigestMismatchError{Uri: r.uri, Expected: r.expected, Actual: actual}
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,150 @@
+package kaffeine
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestParseOCIReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	var tests = []struct {
+		uri  string
+		want OCIReference
+		err  bool
+	}{
+		{"oci://ghcr.io/konveyor/catalog:v1", OCIReference{"ghcr.io", "konveyor/catalog", "v1", ""}, false},
+		{"oci://localhost:5000/catalog", OCIReference{"localhost:5000", "catalog", "latest", ""}, false},
+		{"oci://ghcr.io/konveyor/bins@" + digest + "#logger", OCIReference{"ghcr.io", "konveyor/bins", digest, "logger"}, false},
+		{"oci://ghcr.io", OCIReference{}, true},
+		{"oci://ghcr.io/konveyor/bins@sha256:deadbeef", OCIReference{}, true},
+		{"oci://ghcr.io/Konveyor/catalog:v1", OCIReference{}, true},
+		{"https://ghcr.io/konveyor/catalog:v1", OCIReference{}, true},
+	}
+
+	for _, test := range tests {
+		ref, err := ParseOCIReference(test.uri)
+		if (err != nil) != test.err {
+			t.Errorf("%s: got error %v", test.uri, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if ref != test.want || ref.String() != test.uri && test.want.Reference != "latest" {
+			t.Errorf("%s: got %+v (%s)", test.uri, ref, ref)
+		}
+	}
+}
+
+// An in-process registry serving artifacts that need a token from its token
+// service, which takes bob's password
+type testRegistry struct {
+	*httptest.Server
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	// Number of blobs served
+	pulls int
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	r := &testRegistry{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	r.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			if user, pw, ok := req.BasicAuth(); !ok || user != "bob" || pw != "pw" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token": "secret"}`))
+			return
+		}
+
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, r.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if m, ok := r.manifests[req.URL.Path]; ok {
+			w.Write(m)
+			return
+		}
+		if b, ok := r.blobs[req.URL.Path]; ok {
+			r.pulls++
+			w.Write(b)
+			return
+		}
+		http.NotFound(w, req)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// Pushes an artifact with the given layers by title and returns its uri
+func (r *testRegistry) push(repo string, tag string, layers map[string][]byte) string {
+	manifest := ociManifest{MediaType: ociManifestTypes[0]}
+	for title, content := range layers {
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+		r.blobs["/v2/"+repo+"/blobs/"+digest] = content
+		manifest.Layers = append(manifest.Layers, ociDescriptor{
+			MediaType:   "application/octet-stream",
+			Digest:      digest,
+			Size:        int64(len(content)),
+			Annotations: map[string]string{ociTitleAnnotation: title},
+		})
+	}
+	r.manifests["/v2/"+repo+"/manifests/"+tag], _ = json.Marshal(manifest)
+	return "oci://" + r.Listener.Addr().String() + "/" + repo + ":" + tag
+}
+
+func TestOCIRegistry(t *testing.T) {
+	r := newTestRegistry(t)
+	client := &HTTPClient{Client: r.Client(), Credentials: map[string]Credential{r.Listener.Addr().String(): {Username: "bob", Password: "pw"}}}
+
+	catalog, _ := yaml.Marshal(makeTestCatalog("test", "Test", "v1.0.0"))
+	catalogUri := r.push("konveyor/catalog", "v1", map[string][]byte{"catalog.yaml": catalog})
+	binary := []byte("#!/bin/sh\ncat\n")
+	binUri := r.push("konveyor/bins", "v1", map[string][]byte{"logger": binary, "README": []byte("docs")})
+
+	cm := MakeCatalogManager(t.TempDir())
+	cm.Client = client
+	if err := cm.AddCatalogFromUri(catalogUri); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cm.Functions["konveyor.io/RouteFunction"]; !ok {
+		t.Errorf("function of the oci catalog not loaded")
+	}
+	if _, err := cm.UpdateCatalog(catalogUri); err != nil || r.pulls != 1 {
+		t.Errorf("got %d pulls (%v), want the unchanged catalog not to be pulled again", r.pulls, err)
+	}
+
+	store := MakeBinaryStore(t.TempDir())
+	store.Client = client
+	sum := fmt.Sprintf("%x", sha256.Sum256(binary))
+	if digest, err := store.Add(binUri+"#logger", sum); err != nil || digest != sum {
+		t.Errorf("got binary %s (%v), want %s", digest, err, sum)
+	}
+
+	r.blobs["/v2/konveyor/bins/blobs/sha256:"+sum] = []byte("tampered")
+	var mismatch *DigestMismatchError
+	if _, err := store.Add(binUri+"#logger", ""); !errors.As(err, &mismatch) {
+		t.Errorf("got error %v, want a digest mismatch", err)
+	}
+
+	for _, uri := range []string{binUri, binUri + "#missing", strings.Replace(catalogUri, ":v1", ":v2", 1)} {
+		if _, err := client.Open(uri, ""); err == nil {
+			t.Errorf("%s: expected error", uri)
+		}
+	}
+
+	anonymous := &HTTPClient{Client: r.Client()}
+	if _, err := anonymous.Open(catalogUri, ""); err == nil || !strings.Contains(err.Error(), "authenticate") {
+		t.Errorf("got error %v, want the token to be refused", err)
+	}
+}
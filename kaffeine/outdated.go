@@ -0,0 +1,87 @@
+package kaffeine
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+)
+
+// An installed function that is not at the latest version of its catalog
+type OutdatedFunction struct {
+	Name string `json:"name"`
+	// The installed version
+	Current string `json:"current"`
+	// The version update would install, respecting pins and ranges
+	Wanted string `json:"wanted"`
+	// The highest version in the catalog
+	Latest string `json:"latest"`
+}
+
+// Reports whether update would change the installed version
+func (o OutdatedFunction) UpdateAvailable() bool {
+	return o.Wanted != o.Current
+}
+
+// Compares every installed function with the highest version in the catalogs
+// and returns those that are not at it, sorted by name. Functions that are in
+// no catalog are reported as errors.
+func (fm *FunctionManager) Outdated() (outdated []OutdatedFunction, errs []error) {
+	outdated = []OutdatedFunction{}
+	for groupName, fd := range fm.Installed {
+		functions, err := fm.CatMan.catalogFunctions(fd.CatalogAlias())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("function '%s': %w", groupName, err))
+			continue
+		}
+		catalogFd, ok := functions[groupName]
+		if !ok {
+			errs = append(errs, fmt.Errorf("function '%s' not found in any catalog", groupName))
+			continue
+		}
+
+		o := OutdatedFunction{
+			Name:    groupName,
+			Current: fd.Versions[0].Name,
+			Latest:  catalogFd.GetHighestVersion(fm.AllowPrerelease).Name,
+		}
+		o.Wanted = o.Latest
+
+		requested := fd.RequestedVersion()
+		if fd.Metadata != nil && fd.Metadata.Annotations[IgnoreAutoUpdates] == "true" {
+			o.Wanted = o.Current
+		} else if IsVersionConstraint(requested) {
+			c, err := ParseConstraint(requested)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("function '%s': %w", groupName, err))
+				continue
+			}
+			o.Wanted = o.Current
+			if v, err := catalogFd.GetHighestMatchingVersion(c, fm.AllowPrerelease); err == nil {
+				o.Wanted = v.Name
+			}
+		}
+
+		if o.Current != o.Wanted || o.Current != o.Latest {
+			outdated = append(outdated, o)
+		}
+	}
+
+	sort.Slice(outdated, func(i, j int) bool {
+		return outdated[i].Name < outdated[j].Name
+	})
+
+	return outdated, errs
+}
+
+// Formats the outdated functions as a table
+func OutdatedTable(outdated []OutdatedFunction) []byte {
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tCURRENT\tWANTED\tLATEST")
+	for _, o := range outdated {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", o.Name, o.Current, o.Wanted, o.Latest)
+	}
+	w.Flush()
+	return b.Bytes()
+}
@@ -0,0 +1,118 @@
+package kaffeine
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestOutdated(t *testing.T) {
+	var tests = []struct {
+		fname, newVersion       string
+		current, wanted, latest string
+		update                  bool
+	}{
+		{"example.com/Logger", "", "", "", "", false},
+		{"example.com/Logger", "v2.1.0", "v2.0.0", "v2.1.0", "v2.1.0", true},
+		{"example.com/Logger@^1.0", "v1.3.0", "v1.2.0", "v1.3.0", "v2.0.0", true},
+		{"example.com/Logger@^1.0", "", "v1.2.0", "v1.2.0", "v2.0.0", false},
+		{"example.com/Logger@v1.0.0", "v2.1.0", "v1.0.0", "v1.0.0", "v2.1.0", false},
+		{"example.com/Logger@~2.0", "v3.0.0-rc.1", "", "", "", false},
+	}
+
+	for _, test := range tests {
+		fm := makeTestFunctionManager(t, "v1.0.0", "v1.2.0", "v2.0.0")
+		fd, err := fm.GetExternalFunctionDefinition(test.fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fm.Installed[fd.GroupName()] = fd
+
+		if test.newVersion != "" {
+			fn := fm.CatMan.Functions["example.com/Logger"]
+			fn.Versions = append(fn.Versions, FunctionVersion{Name: test.newVersion})
+			fm.CatMan.Functions["example.com/Logger"] = fn
+		}
+
+		outdated, errs := fm.Outdated()
+		if len(errs) != 0 {
+			t.Errorf("%s: %v", test.fname, errs)
+			continue
+		}
+		if test.current == "" {
+			if len(outdated) != 0 {
+				t.Errorf("%s (%s): got %v, want up to date", test.fname, test.newVersion, outdated)
+			}
+			continue
+		}
+		if len(outdated) != 1 {
+			t.Errorf("%s (%s): got %v, want one outdated function", test.fname, test.newVersion, outdated)
+			continue
+		}
+
+		o := outdated[0]
+		if o.Current != test.current || o.Wanted != test.wanted || o.Latest != test.latest || o.UpdateAvailable() != test.update {
+			t.Errorf("%s (%s): got %+v (update %v), want %s/%s/%s (update %v)", test.fname, test.newVersion, o, o.UpdateAvailable(), test.current, test.wanted, test.latest, test.update)
+		}
+	}
+
+	fm := makeTestFunctionManager(t, "v1.0.0")
+	fm.Installed["example.com/Gone"] = FunctionDefinition{Versions: []FunctionVersion{{Name: "v1.0.0"}}}
+	if _, errs := fm.Outdated(); len(errs) != 1 {
+		t.Errorf("got errors %v, want one for the function in no catalog", errs)
+	}
+}
+
+func TestOutdatedRefreshedCatalog(t *testing.T) {
+	fm := makeTestFunctionManager(t, "v1.0.0")
+	cat := fm.CatMan.Catalogs["file:///test.yaml"]
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := yaml.Marshal(cat)
+		w.Write(b)
+	}))
+	defer server.Close()
+
+	catman := MakeCatalogManager(t.TempDir())
+	fm.CatMan = &catman
+	if err := fm.CatMan.AddCatalogFromUri(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := fm.CatMan.Save(); err != nil {
+		t.Fatal(err)
+	}
+	cacheFile := filepath.Join(fm.CatMan.Directory, fmt.Sprintf("%x", sha1.Sum([]byte(server.URL)))+".yaml")
+	cache, err := os.ReadFile(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, err := fm.GetExternalFunctionDefinition("example.com/Logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm.Installed[fd.GroupName()] = fd
+
+	// The cache is behind the catalog until it is refreshed
+	cat = makeTestFunctionManager(t, "v1.0.0", "v2.0.0").CatMan.Catalogs["file:///test.yaml"]
+	if outdated, _ := fm.Outdated(); len(outdated) != 0 {
+		t.Errorf("got %v before refreshing, want up to date", outdated)
+	}
+	_, errs := fm.CatMan.UpdateAllCatalogs()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	outdated, errs := fm.Outdated()
+	if len(errs) != 0 || len(outdated) != 1 || outdated[0].Latest != "v2.0.0" {
+		t.Errorf("got %v (%v), want v2.0.0 from the refreshed catalog", outdated, errs)
+	}
+	if saved, _ := os.ReadFile(cacheFile); string(saved) != string(cache) {
+		t.Errorf("refreshing changed the cache")
+	}
+}
@@ -0,0 +1,133 @@
+package kaffeine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
+)
+
+// The formats of the --output flag. yaml and json contain the value itself,
+// table is meant for humans and name lists one name per line.
+const (
+	OutputYaml  = "yaml"
+	OutputJson  = "json"
+	OutputTable = "table"
+	OutputName  = "name"
+)
+
+var OutputFormats = []string{OutputYaml, OutputJson, OutputTable, OutputName}
+
+// A value that can be printed in every output format
+type Printable interface {
+	Table() []byte
+	Names() []string
+}
+
+// Renders the value in the given output format
+func Render(format string, v Printable) ([]byte, error) {
+	switch format {
+	case OutputYaml:
+		return yaml.Marshal(v)
+	case OutputJson:
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	case OutputTable:
+		return v.Table(), nil
+	case OutputName:
+		var b bytes.Buffer
+		for _, name := range v.Names() {
+			fmt.Fprintln(&b, name)
+		}
+		return b.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("unknown output format '%s' (supported: %s)", format, strings.Join(OutputFormats, ", "))
+}
+
+func newTable(b *bytes.Buffer) *tabwriter.Writer {
+	return tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+}
+
+// Lists the GroupName of every function in the catalog. Versions are left out
+// even when there is only one, so that list and search print the same names.
+func (fc FunctionCatalog) Names() []string {
+	names := []string{}
+	for _, fd := range fc.Spec.KrmFunctions {
+		names = append(names, fd.GroupName())
+	}
+	return names
+}
+
+func (fc FunctionCatalog) Table() []byte {
+	var b bytes.Buffer
+	w := newTable(&b)
+	fmt.Fprintln(w, "NAME\tVERSIONS\tDESCRIPTION")
+	for _, fd := range fc.Spec.KrmFunctions {
+		versions := make([]FunctionVersion, len(fd.Versions))
+		copy(versions, fd.Versions)
+		SortVersions(versions)
+
+		names := []string{}
+		for i := len(versions) - 1; i >= 0; i-- {
+			names = append(names, versions[i].Name)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", fd.GroupName(), strings.Join(names, ", "), fd.Description)
+	}
+	w.Flush()
+	return b.Bytes()
+}
+
+func (info FunctionInfo) Names() []string {
+	return []string{info.Definition.GroupName()}
+}
+
+func (info FunctionInfo) Table() []byte {
+	return info.Describe()
+}
+
+// The result of FunctionManager.Outdated, printable in every output format
+type OutdatedFunctions []OutdatedFunction
+
+func (o OutdatedFunctions) Names() []string {
+	names := []string{}
+	for _, f := range o {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func (o OutdatedFunctions) Table() []byte {
+	return OutdatedTable(o)
+}
+
+// Lists the values of the config as "key=value"
+func (c Config) Names() []string {
+	names := []string{}
+	for _, v := range c.Values() {
+		names = append(names, v.Key+"="+v.Value)
+	}
+	return names
+}
+
+// Lists the values of the config with the file they came from
+func (c Config) Table() []byte {
+	var b bytes.Buffer
+	w := newTable(&b)
+	fmt.Fprintln(w, "ORIGIN\tKEY\tVALUE")
+	for _, v := range c.Values() {
+		origin := v.Origin
+		if origin == "" {
+			origin = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", origin, v.Key, v.Value)
+	}
+	w.Flush()
+	return b.Bytes()
+}
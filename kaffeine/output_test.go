@@ -0,0 +1,54 @@
+package kaffeine
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	fm := makeTestFunctionManager(t, "v1.0.0", "v1.2.0")
+	fd, err := fm.GetExternalFunctionDefinition("example.com/Logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm.Installed[fd.GroupName()] = fd
+	search, err := fm.SearchCatalog("Logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name   string
+		format string
+		v      Printable
+		want   string
+	}{
+		{"list name", OutputName, fm.InstalledCatalog(), "example.com/Logger\n"},
+		{"search name", OutputName, search, "example.com/Logger\n"},
+		{"search table", OutputTable, search, "NAME                VERSIONS        DESCRIPTION\nexample.com/Logger  v1.2.0, v1.0.0  \n"},
+		{"list yaml", OutputYaml, fm.InstalledCatalog(), "    group: example.com\n"},
+		{"list json", OutputJson, fm.InstalledCatalog(), "\"krmFunctions\": [\n"},
+		{"outdated json", OutputJson, OutdatedFunctions{}, "[]\n"},
+		{"outdated name", OutputName, OutdatedFunctions{{Name: "example.com/Logger"}}, "example.com/Logger\n"},
+		{"config name", OutputName, Config{Catalogs: []CatalogConfig{{Name: "test", Uri: "file:///test.yaml"}}}, "catalogs=test file:///test.yaml\n"},
+	}
+
+	for _, test := range tests {
+		b, err := Render(test.format, test.v)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !strings.Contains(string(b), test.want) {
+			t.Errorf("%s: output %q does not contain %q", test.name, b, test.want)
+		}
+		if test.format == OutputJson && !json.Valid(b) {
+			t.Errorf("%s: invalid json %q", test.name, b)
+		}
+	}
+
+	if _, err := Render("xml", search); err == nil {
+		t.Errorf("expected error for unknown output format")
+	}
+}
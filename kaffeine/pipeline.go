@@ -0,0 +1,82 @@
+package kaffeine
+
+import (
+	"fmt"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// An ordered list of functions. The output ResourceList of each step is the
+// input of the next.
+type Pipeline []PipelineStep
+
+// A single function of a Pipeline. Function is the installed function to run,
+// "Group/Name" optionally followed by "@Version". Name is used to refer to the
+// step in errors and defaults to the function.
+type PipelineStep struct {
+	// required
+	Function string `json:"function"`
+	// optional
+	Name           string                 `json:"name,omitempty"`
+	FunctionConfig map[string]interface{} `json:"functionConfig,omitempty"`
+}
+
+func (s PipelineStep) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Function
+}
+
+// Returns the names of the pipelines in the config, sorted
+func (c *Config) PipelineNames() []string {
+	names := make([]string, 0, len(c.Pipelines))
+	for name := range c.Pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Runs the pipeline with the given name from the config against the input
+// ResourceList and returns the output of its last step. Every function has to
+// be installed before any step is run, and the pipeline stops at the first
+// step that fails.
+func (fm *FunctionManager) RunPipeline(name string, input []byte) (output []byte, err error) {
+	pipeline, ok := fm.Cfg.Pipelines[name]
+	if !ok {
+		return nil, fmt.Errorf("no pipeline named '%s' (available: %v)", name, fm.Cfg.PipelineNames())
+	}
+	if len(pipeline) == 0 {
+		return nil, fmt.Errorf("pipeline '%s' has no steps", name)
+	}
+
+	configs := make([][]byte, len(pipeline))
+	for i, step := range pipeline {
+		if _, err := fm.GetInstalledFunctionDefinition(step.Function); err != nil {
+			return nil, fmt.Errorf("pipeline '%s', step '%s': %w", name, step, err)
+		}
+
+		// Steps without a functionConfig must not see the one of the previous
+		// step, so it is always replaced
+		fnConfig := step.FunctionConfig
+		if fnConfig == nil {
+			fnConfig = map[string]interface{}{}
+		}
+		configs[i], err = yaml.Marshal(fnConfig)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline '%s', step '%s': invalid functionConfig: %w", name, step, err)
+		}
+	}
+
+	output = input
+	for i, step := range pipeline {
+		output, err = fm.RunFunction(step.Function, output, configs[i])
+		if err != nil {
+			return output, fmt.Errorf("pipeline '%s' failed at step %d ('%s'): %w", name, i+1, step, err)
+		}
+	}
+
+	return output, nil
+}
@@ -0,0 +1,95 @@
+package kaffeine
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const testPipelines = `pipelines:
+  rename:
+  - function: example.com/Rename
+    functionConfig:
+      from: wordpress
+      to: blog
+  - name: second rename
+    function: example.com/Rename@v1.0.0
+    functionConfig:
+      from: blog
+      to: site
+  config:
+  - function: example.com/Rename
+    functionConfig:
+      from: wordpress
+      to: blog
+  - function: example.com/Cat
+  failing:
+  - function: example.com/Rename
+    functionConfig:
+      from: wordpress
+      to: blog
+  - name: boom
+    function: example.com/Fail
+  - function: example.com/Cat
+  missing:
+  - function: example.com/Cat
+  - function: example.com/Dog
+`
+
+func TestRunPipeline(t *testing.T) {
+	fm := FunctionManager{Cfg: &Config{}, Installed: map[string]FunctionDefinition{}}
+	if err := yaml.Unmarshal([]byte(testPipelines), fm.Cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	scripts := map[string]string{
+		// Replaces functionConfig.from with functionConfig.to in the items
+		"Rename": `input=$(cat)
+from=$(echo "$input" | sed -n 's/^  from: //p')
+to=$(echo "$input" | sed -n 's/^  to: //p')
+echo "$input" | sed "s+name: $from+name: $to+"`,
+		"Cat":  "cat",
+		"Fail": "cat >/dev/null; exit 1",
+	}
+	for kind, script := range scripts {
+		fd := FunctionDefinition{Group: "example.com", Versions: []FunctionVersion{{Name: "v1.0.0"}}}
+		fd.Names.Kind = kind
+		fd.Metadata = &v1.ObjectMeta{Annotations: map[string]string{
+			LocalBinaryLocation: "file://" + writeScript(t, script),
+		}}
+		fm.Installed[fd.GroupName()] = fd
+	}
+
+	var tests = []struct {
+		pipeline   string
+		wantOutput []string
+		wantErr    string
+	}{
+		{"rename", []string{"name: site", "to: site"}, ""},
+		{"config", []string{"name: blog", "functionConfig: {}"}, ""},
+		{"failing", nil, "failed at step 2 ('boom')"},
+		{"missing", nil, "step 'example.com/Dog'"},
+		{"unknown", nil, "no pipeline named 'unknown'"},
+	}
+
+	for _, test := range tests {
+		output, err := fm.RunPipeline(test.pipeline, []byte(testResourceList))
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: got error %v, want error containing %q", test.pipeline, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.pipeline, err)
+			continue
+		}
+		for _, want := range test.wantOutput {
+			if !strings.Contains(string(output), want) {
+				t.Errorf("%s: output %q does not contain %q", test.pipeline, output, want)
+			}
+		}
+	}
+}
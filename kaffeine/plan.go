@@ -0,0 +1,164 @@
+package kaffeine
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// The changes Save would make to the kaffeine directory, see Plan
+type Plan struct {
+	// Functions as "Group/Name@Version"
+	Added   []string     `json:"added"`
+	Removed []string     `json:"removed"`
+	Changed []PlanChange `json:"changed"`
+	// URIs of the binaries that are not in the store yet
+	Downloads []string `json:"downloads"`
+
+	CatalogsAdded   []string `json:"catalogsAdded"`
+	CatalogsRemoved []string `json:"catalogsRemoved"`
+	CatalogsUpdated []string `json:"catalogsUpdated"`
+}
+
+// A function whose installed version changes
+type PlanChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Reports whether the plan changes anything
+func (p Plan) Empty() bool {
+	return len(p.Added)+len(p.Removed)+len(p.Changed)+len(p.Downloads)+len(p.CatalogsAdded)+len(p.CatalogsRemoved)+len(p.CatalogsUpdated) == 0
+}
+
+func (p Plan) String() string {
+	if p.Empty() {
+		return "No changes\n"
+	}
+
+	var b strings.Builder
+	for _, uri := range p.CatalogsAdded {
+		fmt.Fprintf(&b, "+ catalog %s\n", uri)
+	}
+	for _, uri := range p.CatalogsRemoved {
+		fmt.Fprintf(&b, "- catalog %s\n", uri)
+	}
+	for _, uri := range p.CatalogsUpdated {
+		fmt.Fprintf(&b, "~ catalog %s\n", uri)
+	}
+	for _, fname := range p.Added {
+		fmt.Fprintf(&b, "+ %s\n", fname)
+	}
+	for _, fname := range p.Removed {
+		fmt.Fprintf(&b, "- %s\n", fname)
+	}
+	for _, c := range p.Changed {
+		fmt.Fprintf(&b, "~ %s %s -> %s\n", c.Name, c.From, c.To)
+	}
+	for _, uri := range p.Downloads {
+		fmt.Fprintf(&b, "download %s\n", uri)
+	}
+	return b.String()
+}
+
+// Computes what Save would change in the kaffeine directory and which binaries
+// it would download, without changing anything
+func (fm *FunctionManager) Plan() (p Plan, err error) {
+	p = Plan{
+		Added:           []string{},
+		Removed:         []string{},
+		Changed:         []PlanChange{},
+		Downloads:       []string{},
+		CatalogsAdded:   []string{},
+		CatalogsRemoved: []string{},
+		CatalogsUpdated: []string{},
+	}
+
+	saved, err := fm.savedFunctionDefinitions()
+	if err != nil {
+		return
+	}
+
+	for groupName, fd := range fm.Installed {
+		version := fd.Versions[0].Name
+		if old, ok := saved[groupName]; !ok || len(old.Versions) == 0 {
+			p.Added = append(p.Added, groupName+"@"+version)
+		} else if old.Versions[0].Name != version {
+			p.Changed = append(p.Changed, PlanChange{Name: groupName, From: old.Versions[0].Name, To: version})
+		}
+
+		if len(fd.Versions[0].Runtime.Exec.Platforms) == 0 || fm.isFunctionSaved(fd) {
+			continue
+		}
+		i, err := fd.Versions[0].Runtime.Exec.GetPlatform(fm.platformFor(fd))
+		if err != nil {
+			return p, fmt.Errorf("function '%s': %w", groupName, err)
+		}
+		platform := fd.Versions[0].Runtime.Exec.Platforms[i]
+		if fm.Store.Lookup(fd, platform) == "" {
+			p.Downloads = append(p.Downloads, platform.Uri)
+		}
+	}
+
+	for groupName, fd := range saved {
+		_, installed := fm.Installed[groupName]
+		_, unresolved := fm.Unresolved[groupName]
+		if installed || unresolved {
+			continue
+		}
+		if len(fd.Versions) > 0 {
+			groupName += "@" + fd.Versions[0].Name
+		}
+		p.Removed = append(p.Removed, groupName)
+	}
+
+	// Catalogs are compared with the config and the cache on disk
+	before := map[string]bool{}
+	cfg := MakeConfig(fm.Directory)
+	for _, uri := range cfg.CatalogUris() {
+		before[uri] = true
+	}
+	for uri, cat := range fm.CatMan.Catalogs {
+		if !before[uri] {
+			p.CatalogsAdded = append(p.CatalogsAdded, uri)
+		} else if fm.CatMan.cacheChanged(uri, cat) {
+			p.CatalogsUpdated = append(p.CatalogsUpdated, uri)
+		}
+	}
+	for uri := range fm.CatMan.Unavailable {
+		if !before[uri] {
+			p.CatalogsAdded = append(p.CatalogsAdded, uri)
+		}
+	}
+	for uri := range before {
+		_, loaded := fm.CatMan.Catalogs[uri]
+		_, unavailable := fm.CatMan.Unavailable[uri]
+		if !loaded && !unavailable {
+			p.CatalogsRemoved = append(p.CatalogsRemoved, uri)
+		}
+	}
+
+	for _, list := range [][]string{p.Added, p.Removed, p.Downloads, p.CatalogsAdded, p.CatalogsRemoved, p.CatalogsUpdated} {
+		sort.Strings(list)
+	}
+	sort.Slice(p.Changed, func(i, j int) bool {
+		return p.Changed[i].Name < p.Changed[j].Name
+	})
+
+	return p, nil
+}
+
+// Reports whether the catalog differs from its cached copy on disk
+func (cm *CatalogManager) cacheChanged(uri string, cat FunctionCatalog) bool {
+	cached, err := cm.readCache(uri)
+	if err != nil {
+		return true
+	}
+
+	b, err := yaml.Marshal(cat)
+	return err != nil || !bytes.Equal(b, cached)
+}
@@ -0,0 +1,100 @@
+package kaffeine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	t.Setenv("KAFFEINE_GLOBAL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	fm := makeTestFunctionManager(t, "v1.0.0", "v1.2.0")
+	fm.Directory = t.TempDir()
+	fm.CatMan.Directory = filepath.Join(fm.Directory, "catalogs")
+	cfg := MakeConfig(fm.Directory)
+	fm.Cfg = &cfg
+	fm.Store = MakeBinaryStore(t.TempDir())
+
+	if _, err := fm.AddFunctionDefinition("example.com/Logger@v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fm.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := fm.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Empty() {
+		t.Errorf("got plan %v right after saving, want no changes", p)
+	}
+
+	// Upgrade Logger, change its catalog and add a function from a new catalog
+	fm.Installed["example.com/Logger"], err = fm.GetExternalFunctionDefinition("example.com/Logger@v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := fm.CatMan.Catalogs["file:///test.yaml"]
+	cat.Metadata.Name = "changed"
+	fm.CatMan.Catalogs["file:///test.yaml"] = cat
+
+	bin := writeScript(t, "cat")
+	goos, goarch, _ := ParsePlatform(HostPlatform())
+	other := MakeFunctionCatalog("other")
+	echo := FunctionDefinition{Group: "example.com", Versions: []FunctionVersion{{Name: "v3.0.0"}}}
+	echo.Names.Kind = "Echo"
+	echo.Versions[0].Runtime.Exec.Platforms = []FunctionRuntimePlatform{{Os: goos, Arch: goarch, Uri: "file://" + bin}}
+	other.Spec.KrmFunctions = append(other.Spec.KrmFunctions, echo)
+	if err := fm.CatMan.AddCatalogFromStruct("file:///other.yaml", other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fm.AddFunctionDefinition("example.com/Echo"); err != nil {
+		t.Fatal(err)
+	}
+
+	before := listDir(t, fm.Directory)
+	p, err = fm.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `+ catalog file:///other.yaml
+~ catalog file:///test.yaml
++ example.com/Echo@v3.0.0
+~ example.com/Logger v1.0.0 -> v1.2.0
+download file://` + bin + "\n"
+	if p.String() != want {
+		t.Errorf("got plan\n%s\nwant\n%s", p, want)
+	}
+	if after := listDir(t, fm.Directory); after != before {
+		t.Errorf("planning changed the directory from\n%s\nto\n%s", before, after)
+	}
+
+	fm.RemoveFunctionDefinition("example.com/Logger")
+	fm.CatMan.RemoveCatalog("file:///test.yaml")
+	p, err = fm.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(p.Removed, p.CatalogsRemoved) != "[example.com/Logger@v1.0.0] [file:///test.yaml]" {
+		t.Errorf("got removed %v and %v", p.Removed, p.CatalogsRemoved)
+	}
+}
+
+func listDir(t *testing.T, dir string) string {
+	t.Helper()
+	var b strings.Builder
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s %d %v\n", path, info.Size(), info.ModTime())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
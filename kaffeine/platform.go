@@ -0,0 +1,86 @@
+package kaffeine
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Returns the platform kaffeine is running on, in the format "os/arch"
+func HostPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// Splits a platform in the format "os/arch". Common aliases for
+// architectures, such as "x86_64" and "aarch64", are normalized to their Go
+// names.
+func ParsePlatform(platform string) (os string, arch string, err error) {
+	parts := strings.Split(platform, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid platform '%s', expected 'os/arch'", platform)
+	}
+
+	return strings.ToLower(parts[0]), normalizeArch(parts[1]), nil
+}
+
+func normalizeArch(arch string) string {
+	arch = strings.ToLower(arch)
+	switch arch {
+	case "x86_64", "x86-64", "x64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	case "i386", "i686", "x86":
+		return "386"
+	}
+	return arch
+}
+
+// Returns the index of the platform matching the given "os/arch". If none
+// matches, the error lists the available platforms.
+func (r FunctionRuntimeExec) GetPlatform(platform string) (int, error) {
+	os, arch, err := ParsePlatform(platform)
+	if err != nil {
+		return -1, err
+	}
+
+	available := []string{}
+	for i, p := range r.Platforms {
+		if strings.ToLower(p.Os) == os && normalizeArch(p.Arch) == arch {
+			return i, nil
+		}
+		available = append(available, p.Os+"/"+p.Arch)
+	}
+
+	return -1, fmt.Errorf("no binary available for platform '%s' (available: %s)", platform, strings.Join(available, ", "))
+}
+
+// Returns the index of the platform whose binary was downloaded for the
+// installed function, or -1 if there is none
+func (m FunctionDefinition) installedPlatformIndex() int {
+	if m.Metadata == nil || m.Metadata.Annotations[LocalBinaryLocation] == "" {
+		return -1
+	}
+
+	// Definitions saved before platforms were recorded always used the first
+	platform, ok := m.Metadata.Annotations[Platform]
+	if !ok {
+		return 0
+	}
+
+	i, _ := m.Versions[0].Runtime.Exec.GetPlatform(platform)
+	return i
+}
+
+// Returns the platform to download the binary of the function for: the one
+// requested for this FunctionManager, the one the function was previously
+// installed for, or the host platform.
+func (fm *FunctionManager) platformFor(fd FunctionDefinition) string {
+	if fm.Platform != "" {
+		return fm.Platform
+	}
+	if fd.Metadata != nil && fd.Metadata.Annotations[Platform] != "" {
+		return fd.Metadata.Annotations[Platform]
+	}
+	return HostPlatform()
+}
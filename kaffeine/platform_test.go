@@ -0,0 +1,41 @@
+package kaffeine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetPlatform(t *testing.T) {
+	exec := FunctionRuntimeExec{Platforms: []FunctionRuntimePlatform{
+		{Os: "darwin", Arch: "arm64"},
+		{Os: "linux", Arch: "amd64"},
+		{Os: "linux", Arch: "aarch64"},
+	}}
+
+	var tests = []struct {
+		platform string
+		index    int
+	}{
+		{"linux/amd64", 1},
+		{"linux/x86_64", 1},
+		{"linux/arm64", 2},
+		{"darwin/arm64", 0},
+		{"windows/amd64", -1},
+		{"linux", -1},
+	}
+
+	for _, test := range tests {
+		i, err := exec.GetPlatform(test.platform)
+		if i != test.index {
+			t.Errorf("%s: got %d, want %d", test.platform, i, test.index)
+		}
+		if i < 0 && err == nil {
+			t.Errorf("%s: expected error", test.platform)
+		}
+	}
+
+	_, err := exec.GetPlatform("windows/amd64")
+	if err == nil || !strings.Contains(err.Error(), "darwin/arm64, linux/amd64, linux/aarch64") {
+		t.Errorf("error does not list available platforms: %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package kaffeine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// The fields a search term can be restricted to, as in "tag:security"
+var QueryFields = []string{"name", "group", "tag", "publisher", "maintainer", "description"}
+
+type queryTerm struct {
+	field string
+	value string
+}
+
+// Searches the functions of every catalog, or of a single one if a term is
+// prefixed with "catalog::" as in Search. The query is made of whitespace
+// separated terms that all have to match:
+//   - "field:value" matches the value against a single field (see
+//     QueryFields). Tags have to match exactly, other fields only have to
+//     contain the value.
+//   - any other term is matched against the name, group, tags, publisher,
+//     maintainers and description, tolerating typos in the name
+//
+// Values can be quoted to include whitespace. A term like "Logger@^1.0" only
+// keeps the versions matching the version or range, as in Search. Results are
+// ranked by relevance, then sorted by GroupName.
+func (cm *CatalogManager) Query(query string) (fns []FunctionDefinition, err error) {
+	terms, alias, version, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	functions, err := cm.catalogFunctions(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	var constraint *Constraint
+	if IsVersionConstraint(version) {
+		c, err := ParseConstraint(version)
+		if err != nil {
+			return nil, err
+		}
+		constraint = &c
+	}
+
+	scores := map[string]int{}
+	for groupName, fd := range functions {
+		score, ok := scoreFunction(fd, terms)
+		if !ok {
+			continue
+		}
+
+		if version != "" {
+			var versions []FunctionVersion
+			for _, v := range fd.Versions {
+				if constraint != nil && constraint.Check(v.Name, true) || constraint == nil && VersionNamesEqual(v.Name, version) {
+					versions = append(versions, v)
+				}
+			}
+			if len(versions) == 0 {
+				continue
+			}
+			fd.Versions = versions
+		}
+
+		scores[groupName] = score
+		fns = append(fns, fd)
+	}
+
+	sort.Slice(fns, func(i, j int) bool {
+		a, b := fns[i].GroupName(), fns[j].GroupName()
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		return a < b
+	})
+
+	return fns, nil
+}
+
+// Splits the query into terms, respecting double quotes. The catalog alias and
+// the version of a term like "catalog::Logger@v1" are returned separately.
+func parseQuery(query string) (terms []queryTerm, alias string, version string, err error) {
+	var tokens []string
+	var current strings.Builder
+	quoted, inToken := false, false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inToken = true
+		case unicode.IsSpace(r) && !quoted:
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quoted {
+		return nil, "", "", fmt.Errorf("unterminated quote in query '%s'", query)
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	for _, token := range tokens {
+		if strings.Contains(token, "::") {
+			if alias != "" {
+				return nil, "", "", fmt.Errorf("query '%s' has more than one catalog", query)
+			}
+			alias, token = SplitCatalogAlias(token)
+		}
+
+		term := queryTerm{value: token}
+		if i := strings.Index(token, ":"); i > 0 && !strings.Contains(token[:i], "/") {
+			term.field = strings.ToLower(token[:i])
+			term.value = token[i+1:]
+
+			known := false
+			for _, field := range QueryFields {
+				known = known || field == term.field
+			}
+			if !known {
+				return nil, "", "", fmt.Errorf("unknown search field '%s' (supported: %s)", term.field, strings.Join(QueryFields, ", "))
+			}
+		} else if i := strings.LastIndex(token, "@"); i >= 0 {
+			if version != "" {
+				return nil, "", "", fmt.Errorf("query '%s' has more than one version", query)
+			}
+			version = token[i+1:]
+			term.value = token[:i]
+		}
+
+		term.value = strings.ToLower(term.value)
+		if term.value != "" {
+			terms = append(terms, term)
+		}
+	}
+
+	return terms, alias, version, nil
+}
+
+// Returns how relevant the function is to the terms, and whether it matches
+// all of them
+func scoreFunction(fd FunctionDefinition, terms []queryTerm) (score int, ok bool) {
+	for _, term := range terms {
+		var s int
+		if term.field == "" {
+			s = scoreTerm(fd, term.value)
+		} else if matchField(fd, term.field, term.value) {
+			s = 1
+		}
+		if s == 0 {
+			return 0, false
+		}
+		score += s
+	}
+
+	return score, true
+}
+
+func matchField(fd FunctionDefinition, field string, value string) bool {
+	contains := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), value)
+	}
+
+	switch field {
+	case "name":
+		return contains(fd.Names.Kind)
+	case "group":
+		return contains(fd.Group)
+	case "tag":
+		for _, tag := range fd.Tags {
+			if strings.ToLower(tag) == value {
+				return true
+			}
+		}
+	case "publisher":
+		return contains(fd.Publisher)
+	case "maintainer":
+		for _, m := range functionMaintainers(fd) {
+			if contains(m) {
+				return true
+			}
+		}
+	case "description":
+		return contains(fd.Description)
+	}
+
+	return false
+}
+
+// Ranks how well a single unqualified term matches the function, 0 if it does
+// not match at all. Matches on the name weigh the most.
+func scoreTerm(fd FunctionDefinition, term string) (score int) {
+	kind := strings.ToLower(fd.Names.Kind)
+	groupName := strings.ToLower(fd.GroupName())
+
+	switch {
+	case kind == term || groupName == term:
+		score += 100
+	case strings.HasPrefix(kind, term):
+		score += 60
+	case strings.Contains(groupName, term):
+		score += 40
+	}
+
+	for _, tag := range fd.Tags {
+		if strings.ToLower(tag) == term {
+			score += 30
+			break
+		}
+	}
+	if strings.Contains(strings.ToLower(fd.Publisher), term) {
+		score += 15
+	}
+	if strings.Contains(strings.ToLower(fd.Description), term) {
+		score += 10
+	}
+	for _, m := range functionMaintainers(fd) {
+		if strings.Contains(strings.ToLower(m), term) {
+			score += 5
+			break
+		}
+	}
+
+	if score > 0 {
+		return score
+	}
+
+	// Fuzzy matches on the name: typos, or abbreviations like "sesi" for
+	// "SecretSidecar"
+	maxTypos := 1
+	if len(term) > 5 {
+		maxTypos = 2
+	}
+	if d := levenshtein(term, kind); d <= maxTypos {
+		return 10 - 3*d
+	}
+	if isSubsequence(term, kind) {
+		return 2
+	}
+
+	return 0
+}
+
+// Returns the maintainers of the function and of all its versions
+func functionMaintainers(fd FunctionDefinition) []string {
+	maintainers := append([]string{}, fd.Maintainers...)
+	for _, v := range fd.Versions {
+		maintainers = append(maintainers, v.Maintainers...)
+	}
+	return maintainers
+}
+
+// Reports whether the characters of s appear in t in the same order
+func isSubsequence(s string, t string) bool {
+	i := 0
+	for _, r := range t {
+		if i < len(s) && rune(s[i]) == r {
+			i++
+		}
+	}
+	return i == len(s)
+}
+
+// Returns the edit distance between a and b
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func min3(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
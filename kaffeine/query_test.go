@@ -0,0 +1,153 @@
+package kaffeine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func makeQueryCatalogManager() *CatalogManager {
+	fd := func(group, kind, publisher, description string, tags ...string) FunctionDefinition {
+		fd := FunctionDefinition{Group: group, Publisher: publisher, Description: description, Tags: tags}
+		fd.Names.Kind = kind
+		fd.Versions = []FunctionVersion{{Name: "v1.0.0"}, {Name: "v2.0.0"}}
+		return fd
+	}
+
+	cm := &CatalogManager{Functions: map[string]FunctionDefinition{}}
+	for _, f := range []FunctionDefinition{
+		fd("example.com", "Logger", "example", "Logs the resources"),
+		fd("example.com", "LoggerSidecar", "example", "Adds a sidecar container shipping logs"),
+		fd("konveyor.io", "SecretScanner", "konveyor", "Finds secrets in config maps", "security"),
+		fd("konveyor.io", "PolicyCheck", "konveyor", "Validates resources against security policies", "security", "policy"),
+		fd("other.io", "Labeler", "someone", "Adds labels, maintained by the logger team"),
+	} {
+		cm.Functions[f.GroupName()] = f
+	}
+	maintained := cm.Functions["other.io/Labeler"]
+	maintained.Versions[1].Maintainers = []string{"Jane Doe"}
+	cm.Functions["other.io/Labeler"] = maintained
+
+	return cm
+}
+
+func TestQuery(t *testing.T) {
+	var tests = []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"example.com/Logger", "example.com/LoggerSidecar", "konveyor.io/PolicyCheck", "konveyor.io/SecretScanner", "other.io/Labeler"}},
+		{"logger", []string{"example.com/Logger", "example.com/LoggerSidecar", "other.io/Labeler"}},
+		{"Sidecar", []string{"example.com/LoggerSidecar"}},
+		{"tag:security", []string{"konveyor.io/PolicyCheck", "konveyor.io/SecretScanner"}},
+		{"tag:secur", nil},
+		{"tag:security publisher:konveyor", []string{"konveyor.io/PolicyCheck", "konveyor.io/SecretScanner"}},
+		{"tag:policy publisher:konveyor", []string{"konveyor.io/PolicyCheck"}},
+		{"security", []string{"konveyor.io/PolicyCheck", "konveyor.io/SecretScanner"}},
+		{"secret", []string{"konveyor.io/SecretScanner"}},
+		{`description:"config maps"`, []string{"konveyor.io/SecretScanner"}},
+		{"adds labels", []string{"other.io/Labeler"}},
+		{"maintainer:jane", []string{"other.io/Labeler"}},
+		{"group:konveyor name:check", []string{"konveyor.io/PolicyCheck"}},
+		{"loger", []string{"example.com/Logger", "example.com/LoggerSidecar"}},
+		{"secretscaner", []string{"konveyor.io/SecretScanner"}},
+		{"plchk", []string{"konveyor.io/PolicyCheck"}},
+		{"example.com/Logger", []string{"example.com/Logger", "example.com/LoggerSidecar"}},
+		{"nothing", nil},
+	}
+
+	cm := makeQueryCatalogManager()
+	for _, test := range tests {
+		fds, err := cm.Query(test.query)
+		if err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
+		}
+
+		var got []string
+		for _, fd := range fds {
+			got = append(got, fd.GroupName())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestQueryVersions(t *testing.T) {
+	var tests = []struct {
+		query string
+		want  []string
+	}{
+		{"Logger", []string{"v1.0.0", "v2.0.0"}},
+		{"Logger@v2.0.0", []string{"v2.0.0"}},
+		{"Logger@^1.0", []string{"v1.0.0"}},
+		{"Logger@v3.0.0", nil},
+	}
+
+	cm := makeQueryCatalogManager()
+	for _, test := range tests {
+		fds, err := cm.Query(test.query)
+		if err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
+		}
+
+		var got []string
+		if len(fds) > 0 {
+			for _, v := range fds[0].Versions {
+				got = append(got, v.Name)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got versions %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	cm := makeQueryCatalogManager()
+	for _, query := range []string{"color:blue", `description:"unterminated`, "Logger@v1 Labeler@v2"} {
+		if _, err := cm.Query(query); err == nil {
+			t.Errorf("%s: got no error", query)
+		}
+	}
+}
+
+func TestQueryCatalogAlias(t *testing.T) {
+	var tests = []struct {
+		query string
+		want  []string
+	}{
+		{"mirror::RouteFunction", []string{"v1.0.0", "v1.5.0"}},
+		{"upstream::konveyor.io/RouteFunction", []string{"v1.0.0", "v2.0.0"}},
+		{"file:///mirror.yaml::route", []string{"v1.0.0", "v1.5.0"}},
+		{"mirror:: tag:security", nil},
+		{"upstream::RouteFunction@^1.0", []string{"v1.0.0"}},
+		{"mirror::RouteFunction@v2.0.0", nil},
+	}
+
+	cm := makeTestMirrors(t).CatMan
+	for _, test := range tests {
+		fds, err := cm.Query(test.query)
+		if err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
+		}
+
+		var got []string
+		if len(fds) > 0 {
+			for _, v := range fds[0].Versions {
+				got = append(got, v.Name)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got versions %v, want %v", test.query, got, test.want)
+		}
+	}
+
+	for _, query := range []string{"unknown::RouteFunction", "mirror::RouteFunction upstream::RouteFunction"} {
+		if _, err := cm.Query(query); err == nil {
+			t.Errorf("%s: got no error", query)
+		}
+	}
+}
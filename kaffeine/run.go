@@ -0,0 +1,218 @@
+package kaffeine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// A ResourceList as described by the KRM Functions Specification. Only the
+// fields kaffeine needs to inspect are typed, the items are left untouched.
+type ResourceList struct {
+	APIVersion     string                   `json:"apiVersion"`
+	Kind           string                   `json:"kind"`
+	Items          []map[string]interface{} `json:"items"`
+	FunctionConfig map[string]interface{}   `json:"functionConfig,omitempty"`
+	Results        []FunctionResult         `json:"results,omitempty"`
+}
+
+// A single entry of the "results" field of a ResourceList
+type FunctionResult struct {
+	Message     string                 `json:"message"`
+	Severity    string                 `json:"severity,omitempty"`
+	ResourceRef map[string]interface{} `json:"resourceRef,omitempty"`
+	Field       map[string]interface{} `json:"field,omitempty"`
+	File        map[string]interface{} `json:"file,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+}
+
+func (r FunctionResult) String() string {
+	s := "[" + strings.ToUpper(r.Severity) + "] " + r.Message
+	if r.Severity == "" {
+		s = "[INFO] " + r.Message
+	}
+	if name, ok := r.ResourceRef["name"]; ok {
+		s += fmt.Sprintf(" (%v/%v)", r.ResourceRef["kind"], name)
+	}
+	return s
+}
+
+// Error returned when a KRM function fails. Either the function exited with a
+// non-zero exit code, or it reported results with severity "error".
+type FunctionError struct {
+	Function string
+	ExitCode int
+	Stderr   string
+	Results  []FunctionResult
+}
+
+func (e *FunctionError) Error() string {
+	var b strings.Builder
+	if e.ExitCode != 0 {
+		fmt.Fprintf(&b, "function '%s' exited with code %d", e.Function, e.ExitCode)
+	} else {
+		fmt.Fprintf(&b, "function '%s' reported errors", e.Function)
+	}
+	for _, r := range e.Results {
+		b.WriteString("\n  " + r.String())
+	}
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		b.WriteString("\nstderr:\n" + stderr)
+	}
+	return b.String()
+}
+
+// Returns the installed function with the given name. If a version is given,
+// the installed version has to match it.
+func (fm *FunctionManager) GetInstalledFunctionDefinition(fname string) (fd FunctionDefinition, err error) {
+	group, name, version := ToGroupNameVersion(fname)
+	groupName := group + "/" + name
+
+	fd, ok := fm.Installed[groupName]
+	if !ok {
+		return fd, fmt.Errorf("function '%s' not installed (check spelling?)", groupName)
+	}
+	if version != "" && !VersionNamesEqual(fd.Versions[0].Name, version) {
+		return fd, fmt.Errorf("function '%s' is installed with version '%s', not '%s'", groupName, fd.Versions[0].Name, version)
+	}
+
+	return fd, nil
+}
+
+// Returns the path of the downloaded binary of the function
+func (m FunctionDefinition) LocalBinaryPath() (string, error) {
+	var val string
+	if m.Metadata != nil {
+		val = m.Metadata.Annotations[LocalBinaryLocation]
+	}
+	if val == "" {
+		return "", fmt.Errorf("function '%s' has no local binary (does it have an exec runtime?)", m.GroupName())
+	}
+
+	path := strings.TrimPrefix(val, "file://")
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("local binary of function '%s' not found: %v", m.GroupName(), err)
+	}
+
+	return path, nil
+}
+
+// Runs the installed function with the given name against the input
+// ResourceList and returns the resulting ResourceList. If functionConfig is
+// not empty, it replaces the functionConfig field of the input. Functions with
+// no downloaded binary are run with their container runtime instead.
+func (fm *FunctionManager) RunFunction(fname string, input []byte, functionConfig []byte) (output []byte, err error) {
+	fd, err := fm.GetInstalledFunctionDefinition(fname)
+	if err != nil {
+		return
+	}
+
+	if len(functionConfig) > 0 {
+		input, err = SetFunctionConfig(input, functionConfig)
+		if err != nil {
+			return
+		}
+	}
+
+	// Functions without a downloaded binary run in a container, if they can
+	container := fd.Versions[0].Runtime.Container
+	if fd.installedPlatformIndex() < 0 && container.Image != "" {
+		if fm.SkipDigestVerification {
+			container.Sha256 = ""
+		}
+		return fm.Containers.Run(fd.GroupName(), container, input)
+	}
+
+	binPath, err := fd.LocalBinaryPath()
+	if err != nil {
+		return
+	}
+
+	if platform := fd.Metadata.Annotations[Platform]; platform != "" && platform != HostPlatform() {
+		return nil, fmt.Errorf("function '%s' was installed for platform '%s' and cannot run on '%s'", fd.GroupName(), platform, HostPlatform())
+	}
+
+	return RunExecFunction(fd.GroupName(), binPath, input)
+}
+
+// Sets the functionConfig field of the ResourceList. An empty input results in
+// a ResourceList with no items.
+func SetFunctionConfig(input []byte, functionConfig []byte) ([]byte, error) {
+	rl := map[string]interface{}{}
+	if err := yaml.Unmarshal(input, &rl); err != nil {
+		return nil, fmt.Errorf("invalid ResourceList: %v", err)
+	}
+	if rl == nil {
+		rl = map[string]interface{}{}
+	}
+	if _, ok := rl["apiVersion"]; !ok {
+		rl["apiVersion"] = "config.kubernetes.io/v1"
+		rl["kind"] = "ResourceList"
+	}
+	if _, ok := rl["items"]; !ok {
+		rl["items"] = []interface{}{}
+	}
+
+	fnConfig := map[string]interface{}{}
+	if err := yaml.Unmarshal(functionConfig, &fnConfig); err != nil {
+		return nil, fmt.Errorf("invalid functionConfig: %v", err)
+	}
+	rl["functionConfig"] = fnConfig
+
+	return yaml.Marshal(rl)
+}
+
+// Executes the binary at binPath with input piped to its stdin, following the
+// KRM Functions Specification. Returns a *FunctionError if the function exits
+// with a non-zero exit code or reports results with severity "error".
+func RunExecFunction(fname string, binPath string, input []byte) (output []byte, err error) {
+	return runFunctionCommand(fname, exec.Command(binPath), input)
+}
+
+func runFunctionCommand(fname string, cmd *exec.Cmd, input []byte) (output []byte, err error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	output = stdout.Bytes()
+
+	rl := ResourceList{}
+	parseErr := yaml.Unmarshal(output, &rl)
+
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return output, fmt.Errorf("could not run function '%s': %v", fname, runErr)
+		}
+		return output, &FunctionError{
+			Function: fname,
+			ExitCode: exitErr.ExitCode(),
+			Stderr:   stderr.String(),
+			Results:  rl.Results,
+		}
+	}
+
+	if parseErr != nil {
+		return output, fmt.Errorf("function '%s' returned an invalid ResourceList: %v", fname, parseErr)
+	}
+
+	for _, r := range rl.Results {
+		if r.Severity == "error" {
+			return output, &FunctionError{
+				Function: fname,
+				Stderr:   stderr.String(),
+				Results:  rl.Results,
+			}
+		}
+	}
+
+	return output, nil
+}
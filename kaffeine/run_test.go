@@ -0,0 +1,107 @@
+package kaffeine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func writeScript(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fn")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const testResourceList = `apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: wordpress
+`
+
+func TestRunExecFunction(t *testing.T) {
+	var tests = []struct {
+		name, script string
+		exitCode     int
+		results      int
+		wantErr      bool
+		wantOutput   string
+	}{
+		{"passthrough", "cat", 0, 0, false, "name: wordpress"},
+		{"transform", "sed 's+wordpress+not-wordpress+g'", 0, 0, false, "name: not-wordpress"},
+		{"exit code", "cat >/dev/null; echo boom >&2; exit 3", 3, 0, true, ""},
+		{"error results", "cat; printf 'results:\\n- message: bad\\n  severity: error\\n'", 0, 1, true, ""},
+		{"warning results", "cat; printf 'results:\\n- message: meh\\n  severity: warning\\n'", 0, 0, false, "message: meh"},
+		{"invalid output", "cat >/dev/null; echo '[oops'", 0, 0, true, ""},
+	}
+
+	for _, test := range tests {
+		output, err := RunExecFunction("test/fn", writeScript(t, test.script), []byte(testResourceList))
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name, err, test.wantErr)
+			continue
+		}
+		if !strings.Contains(string(output), test.wantOutput) {
+			t.Errorf("%s: output %q does not contain %q", test.name, output, test.wantOutput)
+		}
+
+		var fnErr *FunctionError
+		if errors.As(err, &fnErr) {
+			if fnErr.ExitCode != test.exitCode || len(fnErr.Results) != test.results {
+				t.Errorf("%s: got exit code %d and %d results, want %d and %d", test.name, fnErr.ExitCode, len(fnErr.Results), test.exitCode, test.results)
+			}
+		}
+	}
+}
+
+func TestSetFunctionConfig(t *testing.T) {
+	for _, input := range []string{testResourceList, ""} {
+		b, err := SetFunctionConfig([]byte(input), []byte("kind: Foo\nspec:\n  a: b\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rl := ResourceList{}
+		if err := yaml.Unmarshal(b, &rl); err != nil {
+			t.Fatal(err)
+		}
+		if rl.Kind != "ResourceList" || rl.FunctionConfig["kind"] != "Foo" {
+			t.Errorf("%q: unexpected ResourceList %+v", input, rl)
+		}
+	}
+}
+
+func TestRunFunction(t *testing.T) {
+	fm := FunctionManager{Installed: map[string]FunctionDefinition{}}
+	fd := FunctionDefinition{Group: "example.com", Versions: []FunctionVersion{{Name: "v1.0.0"}}}
+	fd.Names.Kind = "Cat"
+	fd.Metadata = &v1.ObjectMeta{Annotations: map[string]string{
+		LocalBinaryLocation: "file://" + writeScript(t, "cat"),
+	}}
+	fm.Installed[fd.GroupName()] = fd
+
+	if _, err := fm.RunFunction("example.com/Dog", []byte(testResourceList), nil); err == nil {
+		t.Errorf("expected error running function that is not installed")
+	}
+	if _, err := fm.RunFunction("example.com/Cat@v2.0.0", []byte(testResourceList), nil); err == nil {
+		t.Errorf("expected error running function with wrong version")
+	}
+
+	output, err := fm.RunFunction("example.com/Cat", []byte(testResourceList), []byte("kind: Foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(output), "kind: Foo") {
+		t.Errorf("functionConfig not passed to function, got %q", output)
+	}
+}
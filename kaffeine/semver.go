@@ -0,0 +1,223 @@
+package kaffeine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A semantic version (https://semver.org). Version names in catalogs may be
+// prefixed with "v" and may leave out the minor and patch numbers ("v3" is
+// read as 3.0.0).
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Build      string
+}
+
+// Parses the version name into a Version. Returns an error if the name is not
+// a (possibly partial) semantic version.
+func ParseVersion(name string) (v Version, err error) {
+	s := strings.TrimPrefix(name, "v")
+
+	if i := strings.Index(s, "+"); i >= 0 {
+		v.Build = s[i+1:]
+		s = s[:i]
+		if !validIdentifiers(v.Build, false) {
+			return v, fmt.Errorf("invalid build metadata in version '%s'", name)
+		}
+	}
+
+	if i := strings.Index(s, "-"); i >= 0 {
+		pre := s[i+1:]
+		s = s[:i]
+		if !validIdentifiers(pre, true) {
+			return v, fmt.Errorf("invalid pre-release in version '%s'", name)
+		}
+		v.Prerelease = strings.Split(pre, ".")
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("version '%s' has more than three components", name)
+	}
+
+	nums := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		if !isNumeric(part) || (len(part) > 1 && part[0] == '0') {
+			return v, fmt.Errorf("'%s' is not a semantic version", name)
+		}
+		*nums[i], err = strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return v, fmt.Errorf("'%s' is not a semantic version: %v", name, err)
+		}
+	}
+
+	return v, nil
+}
+
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.IsPrerelease() {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compares the precedence of two versions, returning -1, 0 or 1. As per the
+// specification, build metadata is ignored.
+func (v Version) Compare(o Version) int {
+	if c := compareUint(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+
+	// A version without pre-release has a higher precedence
+	switch {
+	case !v.IsPrerelease() && !o.IsPrerelease():
+		return 0
+	case !v.IsPrerelease():
+		return 1
+	case !o.IsPrerelease():
+		return -1
+	}
+
+	for i := 0; i < len(v.Prerelease) && i < len(o.Prerelease); i++ {
+		if c := compareIdentifier(v.Prerelease[i], o.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareUint(uint64(len(v.Prerelease)), uint64(len(o.Prerelease)))
+}
+
+// Compares two version names, returning -1, 0 or 1. Names are ordered as
+// follows:
+//  1. Semantic versions are compared by precedence. Ties (e.g. "v1" and
+//     "1.0.0", or differing build metadata) are broken by comparing the names
+//     lexicographically so that the order is deterministic.
+//  2. Names that are not semantic versions (e.g. "latest") are always lower
+//     than semantic versions, and are compared lexicographically among
+//     themselves.
+func CompareVersionNames(a, b string) int {
+	va, errA := ParseVersion(a)
+	vb, errB := ParseVersion(b)
+
+	switch {
+	case errA == nil && errB == nil:
+		if c := va.Compare(vb); c != 0 {
+			return c
+		}
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	}
+
+	return strings.Compare(a, b)
+}
+
+// Reports whether the two version names refer to the same version. Names
+// are equal if they are identical, or if both are semantic versions with the
+// same precedence and build metadata (e.g. "v1.0.0" and "1.0.0").
+func VersionNamesEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	va, errA := ParseVersion(a)
+	vb, errB := ParseVersion(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	return va.Compare(vb) == 0 && va.Build == vb.Build
+}
+
+// Reports whether the version name is a semantic version with a pre-release
+func IsPrereleaseName(name string) bool {
+	v, err := ParseVersion(name)
+	return err == nil && v.IsPrerelease()
+}
+
+// Sorts the versions from lowest to highest using CompareVersionNames
+func SortVersions(versions []FunctionVersion) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return CompareVersionNames(versions[i].Name, versions[j].Name) < 0
+	})
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Numeric identifiers are compared numerically and are lower than
+// alphanumeric identifiers, which are compared lexically
+func compareIdentifier(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+
+	switch {
+	case aNum && bNum:
+		if c := compareUint(uint64(len(a)), uint64(len(b))); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func validIdentifiers(s string, prerelease bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, c := range id {
+			if !(c == '-' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')) {
+				return false
+			}
+		}
+		// Numeric pre-release identifiers must not include leading zeroes
+		if prerelease && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package kaffeine
+
+import (
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	var tests = []struct {
+		in, out string
+		err     bool
+	}{
+		{"v1.2.3", "1.2.3", false},
+		{"1.2.3", "1.2.3", false},
+		{"v3", "3.0.0", false},
+		{"v1.2", "1.2.0", false},
+		{"v1.0.0-rc.1", "1.0.0-rc.1", false},
+		{"v1.0.0-alpha+build.5", "1.0.0-alpha+build.5", false},
+		{"v1.0.0+20220801", "1.0.0+20220801", false},
+		{"latest", "", true},
+		{"v01.0.0", "", true},
+		{"v1.0.0.0", "", true},
+		{"v1.0.0-01", "", true},
+		{"v1.0.0-", "", true},
+		{"v1..0", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		v, err := ParseVersion(test.in)
+		if (err != nil) != test.err {
+			t.Errorf("%s: got error %v, want error %v", test.in, err, test.err)
+			continue
+		}
+		if err == nil && v.String() != test.out {
+			t.Errorf("%s: got %s, want %s", test.in, v.String(), test.out)
+		}
+	}
+}
+
+func TestCompareVersionNames(t *testing.T) {
+	// Each entry is lower than the next one
+	var ordered = []string{
+		"alpha",
+		"latest",
+		"v1.0.0-alpha",
+		"v1.0.0-alpha.1",
+		"v1.0.0-alpha.beta",
+		"v1.0.0-beta",
+		"v1.0.0-beta.2",
+		"v1.0.0-beta.11",
+		"v1.0.0-rc.1",
+		"1.0.0",
+		"v1.0.0",
+		"v1.0.2",
+		"v1.0.10",
+		"v2",
+		"v9.0.0",
+		"v10.0.0",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := CompareVersionNames(ordered[i], ordered[j]); got != want {
+				t.Errorf("CompareVersionNames(%s, %s): got %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+}
+
+func TestVersionNamesEqual(t *testing.T) {
+	var tests = []struct {
+		a, b  string
+		equal bool
+	}{
+		{"v1.0.0", "v1.0.0", true},
+		{"v1.0.0", "1.0.0", true},
+		{"v3", "v3.0.0", true},
+		{"latest", "latest", true},
+		{"v1.0.0", "v1.0.1", false},
+		{"v1.0.0+a", "v1.0.0+b", false},
+		{"v1.0.0-rc.1", "v1.0.0", false},
+	}
+
+	for _, test := range tests {
+		if got := VersionNamesEqual(test.a, test.b); got != test.equal {
+			t.Errorf("VersionNamesEqual(%s, %s): got %v, want %v", test.a, test.b, got, test.equal)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package kaffeine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A content-addressed store of function binaries shared by every project of
+// the user. Each download is unpacked once into "sha256/<digest>" and project
+// directories symlink to it.
+type BinaryStore struct {
+	// Usually ~/.cache/kaffeine/store
+	Directory string
+
+	// The client downloads are fetched with
+	Client *HTTPClient
+}
+
+// Returns the directory of the user's binary store. It can be overridden with
+// the KAFFEINE_STORE environment variable.
+func DefaultStoreDirectory() string {
+	if dir := os.Getenv("KAFFEINE_STORE"); dir != "" {
+		return dir
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	return filepath.Join(cacheDir, "kaffeine", "store")
+}
+
+// Creates a BinaryStore. Since projects link into the store, its directory is
+// made absolute.
+func MakeBinaryStore(directory string) BinaryStore {
+	if abs, err := filepath.Abs(directory); err == nil {
+		directory = abs
+	}
+	return BinaryStore{Directory: directory}
+}
+
+// Returns the path of the unpacked download with the given sha256
+func (s BinaryStore) Path(digest string) string {
+	return filepath.Join(s.Directory, "sha256", strings.ToLower(digest))
+}
+
+// Reports whether the download with the given sha256 is in the store
+func (s BinaryStore) Has(digest string) bool {
+	if !IsValidSha256(digest) {
+		return false
+	}
+	_, err := os.Lstat(s.Path(digest))
+	return err == nil
+}
+
+// Downloads the uri into the store and returns its sha256. If expectedSha256
+// is not empty, the download is rejected unless it matches.
+func (s BinaryStore) Add(uri string, expectedSha256 string) (digest string, err error) {
+	// The zero value would store into the working directory
+	if s.Directory == "" {
+		return "", errors.New("binary store has no directory")
+	}
+
+	tmpDir := filepath.Join(s.Directory, "tmp")
+	if err = os.MkdirAll(tmpDir, os.ModePerm); err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Join(s.Directory, "sha256"), os.ModePerm); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(tmpDir, "download-")
+	if err != nil {
+		return
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	digest, err = downloadFile(s.Client, uri, tmp.Name(), expectedSha256)
+	if err != nil {
+		return
+	}
+	if s.Has(digest) {
+		return digest, nil
+	}
+
+	// Unpack next to the final location, then move it into place so that no
+	// one ever sees a partially unpacked entry
+	unpacked := tmp.Name() + ".unpacked"
+	if err = unpackDownload(tmp.Name(), unpacked); err != nil {
+		return "", err
+	}
+	if err = os.Rename(unpacked, s.Path(digest)); err != nil {
+		os.RemoveAll(unpacked)
+		// Someone else added the same content in the meantime
+		if s.Has(digest) {
+			return digest, nil
+		}
+		return "", err
+	}
+
+	return digest, nil
+}
+
+// Links the entry with the given sha256 to linkPath and returns the path of the
+// executable through the link. For archives, the member named bin is used.
+func (s BinaryStore) Link(digest string, linkPath string, bin string) (binPath string, err error) {
+	if !s.Has(digest) {
+		return "", fmt.Errorf("binary with sha256 '%s' not in store", digest)
+	}
+
+	if err = os.RemoveAll(linkPath); err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(linkPath), os.ModePerm); err != nil {
+		return
+	}
+	if err = os.Symlink(s.Path(digest), linkPath); err != nil {
+		return
+	}
+
+	return findBinary(linkPath, bin)
+}
+
+// Returns the sha256 of the download the installed function's binary can be
+// found under in the store, without downloading anything. Returns "" if it is
+// not known.
+func (s BinaryStore) Lookup(fd FunctionDefinition, platform FunctionRuntimePlatform) string {
+	if IsValidSha256(platform.Sha256) && s.Has(platform.Sha256) {
+		return strings.ToLower(platform.Sha256)
+	}
+
+	// Digests that were not declared (or declared as placeholders) are known
+	// from a previous download of the same uri
+	if !IsValidSha256(platform.Sha256) && fd.Metadata != nil && fd.Metadata.Annotations[OriginalBinaryLocation] == platform.Uri {
+		if digest := fd.Metadata.Annotations[BinarySha256]; s.Has(digest) {
+			return digest
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,82 @@
+package kaffeine
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinaryStoreShared(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "fn.tar")
+	content := writeTestArchive(t, archiveTar, []testMember{{name: "fn-linux", content: "#!/bin/sh\ncat\n"}})
+	if err := os.Rename(content, src); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(src)
+
+	fd := FunctionDefinition{Group: "example.com", Versions: []FunctionVersion{{Name: "v1.0.0"}}}
+	fd.Names.Kind = "Cat"
+	fd.Versions[0].Runtime.Exec.Platforms = []FunctionRuntimePlatform{{
+		Bin: "fn-linux", Os: "linux", Arch: "amd64", Uri: "file://" + src, Sha256: fmt.Sprintf("%x", sha256.Sum256(b)),
+	}}
+	setRequestedVersion(&fd, "")
+
+	store := MakeBinaryStore(filepath.Join(dir, "store"))
+	var binPaths []string
+	for i := 0; i < 2; i++ {
+		fm := FunctionManager{Directory: t.TempDir(), Store: store, Platform: "linux/amd64", Installed: map[string]FunctionDefinition{fd.GroupName(): fd}}
+		saved, err := fm.SaveFunctionDefinition(fd.GroupName())
+		if err != nil {
+			t.Fatalf("project %d: %v", i, err)
+		}
+		path, err := saved.LocalBinaryPath()
+		if err != nil {
+			t.Fatalf("project %d: %v", i, err)
+		}
+		binPaths = append(binPaths, path)
+
+		// The second project must not download anything
+		os.Remove(src)
+	}
+
+	for _, path := range binPaths {
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if real != filepath.Join(store.Path(fd.Versions[0].Runtime.Exec.Platforms[0].Sha256), "fn-linux") {
+			t.Errorf("binary %s does not point into the store (%s)", path, real)
+		}
+	}
+}
+
+func TestBinaryStoreUndeclaredDigest(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "fn")
+	os.WriteFile(src, []byte("#!/bin/sh\ncat\n"), 0755)
+
+	fd := FunctionDefinition{Group: "example.com", Versions: []FunctionVersion{{Name: "v1.0.0"}}}
+	fd.Names.Kind = "Cat"
+	fd.Versions[0].Runtime.Exec.Platforms = []FunctionRuntimePlatform{{Os: "linux", Arch: "amd64", Uri: "file://" + src, Sha256: "deadbeef"}}
+	setRequestedVersion(&fd, "")
+
+	store := MakeBinaryStore(filepath.Join(dir, "store"))
+	fm := FunctionManager{Directory: t.TempDir(), Store: store, Platform: "linux/amd64", Installed: map[string]FunctionDefinition{fd.GroupName(): fd}}
+	if _, err := fm.SaveFunctionDefinition(fd.GroupName()); err == nil {
+		t.Fatalf("expected error for placeholder digest")
+	}
+
+	fm.SkipDigestVerification = true
+	saved, err := fm.SaveFunctionDefinition(fd.GroupName())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once downloaded, the recorded digest finds the binary in the store
+	if digest := store.Lookup(saved, fd.Versions[0].Runtime.Exec.Platforms[0]); digest != saved.Metadata.Annotations[BinarySha256] {
+		t.Errorf("lookup returned '%s', want '%s'", digest, saved.Metadata.Annotations[BinarySha256])
+	}
+}
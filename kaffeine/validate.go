@@ -0,0 +1,326 @@
+package kaffeine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// The apiVersions and kinds a catalog may declare
+var CatalogAPIVersions = []string{"config.kubernetes.io/v1alpha1"}
+var CatalogKinds = []string{"KRMFunctionCatalog", "Catalog"}
+
+// A problem found in a catalog, see ValidateCatalog
+type CatalogProblem struct {
+	// Position in the catalog file, 0 if unknown
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Path to the offending field, as in "spec.krmFunctions[0].names.kind"
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (p CatalogProblem) String() string {
+	s := ""
+	if p.Line > 0 {
+		s = fmt.Sprintf("line %d: ", p.Line)
+	}
+	if p.Path != "" {
+		s += p.Path + ": "
+	}
+	return s + p.Message
+}
+
+// The problems of an invalid catalog, returned as an error in strict mode
+type InvalidCatalogError struct {
+	Uri      string
+	Problems []CatalogProblem
+}
+
+func (e *InvalidCatalogError) Error() string {
+	lines := []string{fmt.Sprintf("catalog '%s' is invalid:", e.Uri)}
+	for _, p := range e.Problems {
+		lines = append(lines, "  "+p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Checks the catalog for a known apiVersion and kind, required fields,
+// unknown fields and duplicates. Returns every problem found, in the order
+// they appear in the file.
+func ValidateCatalog(data []byte) []CatalogProblem {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return []CatalogProblem{{Message: strings.TrimPrefix(err.Error(), "yaml: ")}}
+	}
+	if len(doc.Content) == 0 {
+		return []CatalogProblem{{Message: "catalog is empty"}}
+	}
+
+	v := &catalogValidator{problems: []CatalogProblem{}}
+	v.catalog(doc.Content[0])
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		return v.problems[i].Line < v.problems[j].Line
+	})
+	return v.problems
+}
+
+type catalogValidator struct {
+	problems []CatalogProblem
+}
+
+func (v *catalogValidator) report(n *yamlv3.Node, path string, format string, args ...interface{}) {
+	v.problems = append(v.problems, CatalogProblem{
+		Line:    n.Line,
+		Column:  n.Column,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Returns the values of the mapping by key, reporting unknown keys. Keys
+// listed in required are reported when missing or empty.
+func (v *catalogValidator) fields(n *yamlv3.Node, path string, known []string, required ...string) map[string]*yamlv3.Node {
+	// Empty values are reported as missing by the parent
+	if isNull(n) {
+		return nil
+	}
+	if n.Kind != yamlv3.MappingNode {
+		v.report(n, path, "expected a mapping")
+		return nil
+	}
+
+	fields := map[string]*yamlv3.Node{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		isKnown := false
+		for _, k := range known {
+			isKnown = isKnown || k == key.Value
+		}
+		if !isKnown {
+			v.report(key, fieldPath(path, key.Value), "unknown field")
+			continue
+		}
+		fields[key.Value] = value
+	}
+
+	for _, k := range required {
+		if value, ok := fields[k]; !ok || isNull(value) || value.Kind == yamlv3.ScalarNode && value.Value == "" {
+			v.report(n, fieldPath(path, k), "is required")
+		}
+	}
+
+	return fields
+}
+
+// Returns the value of the scalar, reporting other kinds of nodes
+func (v *catalogValidator) scalar(n *yamlv3.Node, path string) string {
+	if n == nil || isNull(n) {
+		return ""
+	}
+	if n.Kind != yamlv3.ScalarNode {
+		v.report(n, path, "expected a string")
+		return ""
+	}
+	return n.Value
+}
+
+// Returns the items of the sequence, reporting other kinds of nodes
+func (v *catalogValidator) sequence(n *yamlv3.Node, path string) []*yamlv3.Node {
+	if n == nil || isNull(n) {
+		return nil
+	}
+	if n.Kind != yamlv3.SequenceNode {
+		v.report(n, path, "expected a list")
+		return nil
+	}
+	return n.Content
+}
+
+func (v *catalogValidator) oneOf(n *yamlv3.Node, path string, allowed []string) {
+	value := v.scalar(n, path)
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if a == value {
+			return
+		}
+	}
+	v.report(n, path, "'%s' is not one of %s", value, strings.Join(allowed, ", "))
+}
+
+func (v *catalogValidator) catalog(n *yamlv3.Node) {
+	fields := v.fields(n, "", []string{"apiVersion", "kind", "metadata", "spec"}, "apiVersion", "kind", "spec")
+	if fields == nil {
+		return
+	}
+
+	v.oneOf(fields["apiVersion"], "apiVersion", CatalogAPIVersions)
+	v.oneOf(fields["kind"], "kind", CatalogKinds)
+
+	spec, ok := fields["spec"]
+	if !ok {
+		return
+	}
+	specFields := v.fields(spec, "spec", []string{"krmFunctions"}, "krmFunctions")
+
+	seen := map[string]int{}
+	for i, fn := range v.sequence(specFields["krmFunctions"], "spec.krmFunctions") {
+		path := fmt.Sprintf("spec.krmFunctions[%d]", i)
+		groupName := v.function(fn, path)
+		if groupName == "" {
+			continue
+		}
+		if first, ok := seen[groupName]; ok {
+			v.report(fn, path, "function '%s' is already defined in spec.krmFunctions[%d]", groupName, first)
+		} else {
+			seen[groupName] = i
+		}
+	}
+}
+
+// Validates the function and returns its GroupName, "" if incomplete
+func (v *catalogValidator) function(n *yamlv3.Node, path string) string {
+	fields := v.fields(n, path, []string{"group", "description", "publisher", "names", "versions", "home", "maintainers", "tags", "metadata"}, "group", "names", "versions")
+	if fields == nil {
+		return ""
+	}
+
+	group := v.scalar(fields["group"], fieldPath(path, "group"))
+	v.scalar(fields["description"], fieldPath(path, "description"))
+	v.scalar(fields["publisher"], fieldPath(path, "publisher"))
+	v.scalar(fields["home"], fieldPath(path, "home"))
+	for _, key := range []string{"maintainers", "tags"} {
+		for i, item := range v.sequence(fields[key], fieldPath(path, key)) {
+			v.scalar(item, fmt.Sprintf("%s.%s[%d]", path, key, i))
+		}
+	}
+
+	kind := ""
+	if names, ok := fields["names"]; ok {
+		nameFields := v.fields(names, fieldPath(path, "names"), []string{"kind"}, "kind")
+		kind = v.scalar(nameFields["kind"], fieldPath(path, "names.kind"))
+	}
+
+	versions := v.sequence(fields["versions"], fieldPath(path, "versions"))
+	if fields["versions"] != nil && fields["versions"].Kind == yamlv3.SequenceNode && len(versions) == 0 {
+		v.report(fields["versions"], fieldPath(path, "versions"), "must not be empty")
+	}
+	seen := map[string]int{}
+	for i, version := range versions {
+		versionPath := fmt.Sprintf("%s.versions[%d]", path, i)
+		name := v.version(version, versionPath)
+		if name == "" {
+			continue
+		}
+		if first, ok := seen[name]; ok {
+			v.report(version, versionPath, "version '%s' is already defined in %s.versions[%d]", name, path, first)
+		} else {
+			seen[name] = i
+		}
+	}
+
+	if group == "" || kind == "" {
+		return ""
+	}
+	return group + "/" + kind
+}
+
+// Validates the version and returns its name, "" if missing
+func (v *catalogValidator) version(n *yamlv3.Node, path string) string {
+	fields := v.fields(n, path, []string{"name", "idempotent", "usage", "examples", "license", "runtime", "maintainers"}, "name", "runtime")
+	if fields == nil {
+		return ""
+	}
+
+	// Names that are not semantic versions, like "latest", are allowed, but
+	// have to be usable in "group/name@version"
+	name := v.scalar(fields["name"], fieldPath(path, "name"))
+	if strings.Contains(name, "@") || IsVersionConstraint(name) {
+		v.report(fields["name"], fieldPath(path, "name"), "'%s' cannot be requested as a version, it reads as a range or contains '@'", name)
+	}
+
+	runtime, ok := fields["runtime"]
+	if !ok {
+		return name
+	}
+	runtimePath := fieldPath(path, "runtime")
+	runtimeFields := v.fields(runtime, runtimePath, []string{"container", "exec"})
+	if runtimeFields == nil {
+		return name
+	}
+
+	// Catalogs written by kaffeine have an empty container or empty platforms
+	// when the function only has the other
+	hasContainer, hasExec := false, false
+
+	if container, ok := runtimeFields["container"]; ok {
+		containerPath := fieldPath(runtimePath, "container")
+		c := v.fields(container, containerPath, []string{"image", "sha256", "requireNetwork", "requireStorageMount"})
+		image := v.scalar(c["image"], fieldPath(containerPath, "image"))
+		v.sha256(c["sha256"], fieldPath(containerPath, "sha256"))
+
+		hasContainer = image != ""
+		others := len(c)
+		if _, ok := c["image"]; ok {
+			others--
+		}
+		if !hasContainer && others > 0 {
+			v.report(container, fieldPath(containerPath, "image"), "is required")
+		}
+	}
+
+	if exec, ok := runtimeFields["exec"]; ok {
+		execPath := fieldPath(runtimePath, "exec")
+		e := v.fields(exec, execPath, []string{"platforms"})
+		platforms := v.sequence(e["platforms"], fieldPath(execPath, "platforms"))
+		hasExec = len(platforms) > 0
+		seen := map[string]int{}
+		for i, platform := range platforms {
+			platformPath := fmt.Sprintf("%s.platforms[%d]", execPath, i)
+			p := v.fields(platform, platformPath, []string{"bin", "os", "arch", "uri", "sha256"}, "bin", "os", "arch", "uri", "sha256")
+			values := map[string]string{}
+			for _, key := range []string{"bin", "os", "arch", "uri"} {
+				values[key] = v.scalar(p[key], fieldPath(platformPath, key))
+			}
+			v.sha256(p["sha256"], fieldPath(platformPath, "sha256"))
+
+			os, arch := values["os"], values["arch"]
+			if os == "" || arch == "" {
+				continue
+			}
+			hostPlatform := strings.ToLower(os) + "/" + normalizeArch(arch)
+			if first, ok := seen[hostPlatform]; ok {
+				v.report(platform, platformPath, "platform '%s' is already defined in %s.platforms[%d]", hostPlatform, execPath, first)
+			} else {
+				seen[hostPlatform] = i
+			}
+		}
+	}
+
+	if !hasContainer && !hasExec {
+		v.report(runtime, runtimePath, "requires a container image or exec platforms")
+	}
+
+	return name
+}
+
+func (v *catalogValidator) sha256(n *yamlv3.Node, path string) {
+	if sha := v.scalar(n, path); sha != "" && !IsValidSha256(sha) {
+		v.report(n, path, "'%s' is not a sha256 hex digest", sha)
+	}
+}
+
+func isNull(n *yamlv3.Node) bool {
+	return n.Kind == yamlv3.ScalarNode && n.Tag == "!!null"
+}
+
+func fieldPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,210 @@
+package kaffeine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const validCatalog = `apiVersion: config.kubernetes.io/v1alpha1
+kind: KRMFunctionCatalog
+metadata:
+  name: test
+spec:
+  krmFunctions:
+  - group: example.com
+    names:
+      kind: Logger
+    description: Logs
+    versions:
+    - name: v1.0.0
+      runtime:
+        container:
+          image: example.com/logger:v1.0.0
+        exec:
+          platforms:
+          - bin: logger
+            os: linux
+            arch: amd64
+            uri: https://example.com/logger.tar.gz
+            sha256: 3b1c9a3e8f6d2b4a5c7e9f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c
+`
+
+func TestValidateCatalog(t *testing.T) {
+	var tests = []struct {
+		catalog string
+		want    []string
+	}{
+		{validCatalog, []string{}},
+		{`apiVersion: config.kubernetes.io/v1alpha1
+kind: Catalog
+spec:
+  krmFunctions: []
+`, []string{}},
+		{`apiVersion: config.kubernetes.io/v1alpha1
+kind: Catalog
+spec:
+  krmFunctions:
+  - group: example.com
+    names:
+      kind: Logger
+    versions:
+    - name: latest
+      runtime:
+        container:
+          image: example.com/logger:latest
+    - name: nightly@2024
+      runtime:
+        container:
+          image: example.com/logger:nightly
+`, []string{
+			"line 13: spec.krmFunctions[0].versions[1].name: 'nightly@2024' cannot be requested as a version, it reads as a range or contains '@'",
+		}},
+		{`apiVersion: v2
+kind: FunctionCatalog
+spec:
+  krmFunctions: []
+`, []string{
+			"line 1: apiVersion: 'v2' is not one of config.kubernetes.io/v1alpha1",
+			"line 2: kind: 'FunctionCatalog' is not one of KRMFunctionCatalog, Catalog",
+		}},
+		{`kind: Catalog
+`, []string{
+			"line 1: apiVersion: is required",
+			"line 1: spec: is required",
+		}},
+		{`apiVersion: config.kubernetes.io/v1alpha1
+kind: Catalog
+spec:
+  krmFunctions:
+  - group: example.com
+    names: {}
+    colour: blue
+    versions:
+    - name: 1.x
+      runtime: {}
+    - name: v1.0.0
+    - name: v1.0.0
+      runtime:
+        exec:
+          platforms:
+          - os: linux
+            arch: x86_64
+            uri: https://example.com/a
+            bin: a
+            sha256: deadbeef
+          - os: linux
+            arch: amd64
+            uri: https://example.com/b
+            bin: b
+  - group: example.com
+    names:
+      kind: Logger
+    versions: []
+  - group: example.com
+    names:
+      kind: Logger
+    versions: v1.0.0
+`, []string{
+			"line 6: spec.krmFunctions[0].names.kind: is required",
+			"line 7: spec.krmFunctions[0].colour: unknown field",
+			"line 9: spec.krmFunctions[0].versions[0].name: '1.x' cannot be requested as a version, it reads as a range or contains '@'",
+			"line 10: spec.krmFunctions[0].versions[0].runtime: requires a container image or exec platforms",
+			"line 11: spec.krmFunctions[0].versions[1].runtime: is required",
+			"line 12: spec.krmFunctions[0].versions[2]: version 'v1.0.0' is already defined in spec.krmFunctions[0].versions[1]",
+			"line 20: spec.krmFunctions[0].versions[2].runtime.exec.platforms[0].sha256: 'deadbeef' is not a sha256 hex digest",
+			"line 21: spec.krmFunctions[0].versions[2].runtime.exec.platforms[1].sha256: is required",
+			"line 21: spec.krmFunctions[0].versions[2].runtime.exec.platforms[1]: platform 'linux/amd64' is already defined in spec.krmFunctions[0].versions[2].runtime.exec.platforms[0]",
+			"line 28: spec.krmFunctions[1].versions: must not be empty",
+			"line 29: spec.krmFunctions[2]: function 'example.com/Logger' is already defined in spec.krmFunctions[1]",
+			"line 32: spec.krmFunctions[2].versions: expected a list",
+		}},
+		{string(marshalTestCatalog()), []string{}},
+		{`apiVersion: config.kubernetes.io/v1alpha1
+kind: Catalog
+spec:
+  krmFunctions:
+  - group: example.com
+    names:
+      kind: Logger
+    versions:
+    - name: v1.0.0
+      runtime:
+        container:
+          sha256: 3b1c9a3e8f6d2b4a5c7e9f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c
+        exec:
+          platforms: []
+`, []string{
+			"line 11: spec.krmFunctions[0].versions[0].runtime: requires a container image or exec platforms",
+			"line 12: spec.krmFunctions[0].versions[0].runtime.container.image: is required",
+		}},
+		{"spec: [", []string{"line 1: did not find expected node content"}},
+	}
+
+	for _, test := range tests {
+		got := []string{}
+		for _, p := range ValidateCatalog([]byte(test.catalog)) {
+			got = append(got, p.String())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("got problems\n%v\nwant\n%v", got, test.want)
+		}
+	}
+}
+
+// A catalog as written by kaffeine, with empty containers and platforms
+func marshalTestCatalog() []byte {
+	cat := MakeFunctionCatalog("test")
+	fd := FunctionDefinition{Group: "example.com"}
+	fd.Names.Kind = "Logger"
+	container := FunctionVersion{Name: "v1.0.0"}
+	container.Runtime.Container.Image = "example.com/logger:v1.0.0"
+	exec := FunctionVersion{Name: "v2.0.0"}
+	exec.Runtime.Exec.Platforms = []FunctionRuntimePlatform{{Bin: "logger", Os: "linux", Arch: "amd64", Uri: "https://example.com/logger", Sha256: "3b1c9a3e8f6d2b4a5c7e9f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c"}}
+	fd.Versions = []FunctionVersion{container, exec}
+	cat.Spec.KrmFunctions = []FunctionDefinition{fd}
+
+	b, _ := yaml.Marshal(cat)
+	return b
+}
+
+func TestStrictCatalogManager(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	err := os.WriteFile(path, []byte("apiVersion: config.kubernetes.io/v1alpha1\nkind: Catalog\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm := MakeCatalogManager(t.TempDir())
+	if _, err := cm.GetExternalCatalog("file://" + path); err != nil {
+		t.Errorf("got %v, want invalid catalog accepted", err)
+	}
+
+	cm.Strict = true
+	_, err = cm.GetExternalCatalog("file://" + path)
+	var invalid *InvalidCatalogError
+	if !errors.As(err, &invalid) || len(invalid.Problems) != 1 {
+		t.Errorf("got %v, want the missing spec reported", err)
+	}
+}
+
+func TestValidateExampleCatalogs(t *testing.T) {
+	paths, err := filepath.Glob("../examples/catalogs/*.yaml")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no example catalogs found: %v", err)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range ValidateCatalog(data) {
+			t.Errorf("%s: %s", path, p)
+		}
+	}
+}
@@ -2,14 +2,22 @@ package main
 
 import (
 	"log"
+	"strings"
 
+	"github.com/konveyor/kaffeine/cmd/catalog"
 	"github.com/konveyor/kaffeine/cmd/config"
+	"github.com/konveyor/kaffeine/cmd/info"
+	"github.com/konveyor/kaffeine/cmd/initialize"
 	"github.com/konveyor/kaffeine/cmd/install"
 	"github.com/konveyor/kaffeine/cmd/list"
+	"github.com/konveyor/kaffeine/cmd/outdated"
 	"github.com/konveyor/kaffeine/cmd/remove"
+	"github.com/konveyor/kaffeine/cmd/run"
+	"github.com/konveyor/kaffeine/cmd/runpipeline"
 	"github.com/konveyor/kaffeine/cmd/search"
 	"github.com/konveyor/kaffeine/cmd/update"
 	"github.com/konveyor/kaffeine/cmd/version"
+	"github.com/konveyor/kaffeine/kaffeine"
 
 	"github.com/spf13/cobra"
 )
@@ -19,14 +27,21 @@ func main() {
 		Use:   "kaffeine",
 		Short: "kaffeine is a KRM Function Manager",
 	}
+	rootCmd.PersistentFlags().StringP("output", "o", "", "output format of list, search, info, outdated and config list: "+strings.Join(kaffeine.OutputFormats, ", "))
 
 	rootCmd.AddCommand(version.NewVersionCommand())
+	rootCmd.AddCommand(initialize.NewInitCommand())
 	rootCmd.AddCommand(config.NewConfigCommand())
+	rootCmd.AddCommand(catalog.NewCatalogCommand())
 	rootCmd.AddCommand(list.NewListCommand())
 	rootCmd.AddCommand(search.NewSearchCommand())
+	rootCmd.AddCommand(info.NewInfoCommand())
 	rootCmd.AddCommand(install.NewInstallCommand())
 	rootCmd.AddCommand(remove.NewRemoveCommand())
 	rootCmd.AddCommand(update.NewUpdateCommand())
+	rootCmd.AddCommand(outdated.NewOutdatedCommand())
+	rootCmd.AddCommand(run.NewRunCommand())
+	rootCmd.AddCommand(runpipeline.NewRunPipelineCommand())
 
 	rootErr := rootCmd.Execute()
 	if rootErr != nil {